@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	httpBearerCommand = flag.String("http-bearer-command", "", "Shell command printing a token sent as \"Authorization: Bearer <token>\" by HTTP checks, e.g. \"gcloud auth print-access-token\"")
+	httpBearerTTL     = flag.Duration("http-bearer-ttl", 5*time.Minute, "How long a token printed by --http-bearer-command is reused before running the command again")
+)
+
+// bearerTokenCache shares the token of --http-bearer-command between every
+// HTTP resource, so the command runs at most once per --http-bearer-ttl.
+type bearerTokenCache struct {
+	mu      sync.Mutex
+	token   string
+	fetched time.Time
+}
+
+var bearerTokens bearerTokenCache
+
+// get returns the cached token, running the command when it expired. The
+// token is never included in errors or logs.
+func (c *bearerTokenCache) get(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && time.Since(c.fetched) < *httpBearerTTL {
+		return c.token, nil
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", *httpBearerCommand)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", errors.Wrapf(err, "--http-bearer-command failed: %s", msg)
+		}
+		return "", errors.Wrap(err, "--http-bearer-command failed")
+	}
+	token := strings.TrimSpace(stdout.String())
+	if token == "" {
+		return "", errors.New("--http-bearer-command printed no token")
+	}
+	c.token, c.fetched = token, time.Now()
+	logVerbose("fetched a new token with --http-bearer-command")
+	return token, nil
+}
+
+// invalidate drops the cached token, e.g. after the server rejected it.
+func (c *bearerTokenCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = ""
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	retryBudget = flag.Int("retry-budget", 0, "Maximum number of check attempts across all resources; once used up, resources that are not ready yet fail (0 for no limit)")
+)
+
+// attemptBudget is a number of attempts shared by several waits.
+type attemptBudget struct {
+	mu    sync.Mutex
+	limit int
+	used  int
+}
+
+// take uses up one attempt, reporting false once none are left.
+func (b *attemptBudget) take() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.used >= b.limit {
+		return false
+	}
+	b.used++
+	return true
+}
+
+// usage returns the attempts used so far and the limit.
+func (b *attemptBudget) usage() (int, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used, b.limit
+}
+
+var probeBudgetOnce sync.Once
+var probeBudgetValue *attemptBudget
+
+// probeBudget returns the budget shared by every wait for --retry-budget, or
+// nil when there is no limit.
+func probeBudget() *attemptBudget {
+	probeBudgetOnce.Do(func() {
+		if *retryBudget > 0 {
+			probeBudgetValue = &attemptBudget{limit: *retryBudget}
+		}
+	})
+	return probeBudgetValue
+}
+
+func validateBudgetFlag() error {
+	if *retryBudget < 0 {
+		return errors.Errorf("invalid --retry-budget %d, must not be negative", *retryBudget)
+	}
+	return nil
+}
+
+// budgetExhaustedError reports that the shared budget ran out before the
+// resource became available. As with timeouts, the last error is only quoted,
+// so the run exits with 1 whatever it was.
+func budgetExhaustedError(limit int, lastErr error) error {
+	if lastErr == nil {
+		return errors.Errorf("retry budget of %d attempts exhausted", limit)
+	}
+	return errors.Errorf("retry budget of %d attempts exhausted; last error: %v", limit, lastErr)
+}
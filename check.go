@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// runCheck implements "awfi check": every resource is probed exactly once,
+// concurrently, and reported as ready or not without retrying.
+func runCheck(args []string) {
+	specs, group, checkers, ok := parseRun(args)
+	if !ok {
+		return
+	}
+
+	start := time.Now()
+	results := make([]resourceResult, len(specs))
+	var wg sync.WaitGroup
+	for i := range specs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = checkOnce(context.Background(), specs[i], checkers[i])
+		}(i)
+	}
+	wg.Wait()
+
+	ready := reportResults(os.Stdout, results, time.Since(start))
+	if group != nil {
+		ready = reportGroup(os.Stdout, group, results)
+	}
+	if *statusFile != "" {
+		if err := writeStatusFile(*statusFile, ready, results); err != nil {
+			fmt.Println(err)
+		}
+	}
+	if !ready {
+		os.Exit(failureExitCode(results))
+	}
+}
+
+// checkOnce runs a single attempt, bounded by the resource's timeout like a
+// whole wait would be.
+func checkOnce(ctx context.Context, spec resourceSpec, checker ResourceChecker) resourceResult {
+	ctx, cancel := context.WithTimeout(ctx, spec.waitTimeout())
+	defer cancel()
+	ctx = withCheckTimeout(ctx, spec.Timeout)
+
+	if closer, ok := checker.(io.Closer); ok {
+		defer func() {
+			_ = closer.Close()
+		}()
+	}
+
+	if limiter := probeLimiter(); limiter != nil {
+		if err := limiter.Wait(ctx); err != nil {
+			return newResourceResult(spec, waitStats{}, errors.Wrap(err, "rate limited by --max-rate"))
+		}
+	}
+
+	if budget := probeBudget(); budget != nil && !budget.take() {
+		_, limit := budget.usage()
+		return newResourceResult(spec, waitStats{}, budgetExhaustedError(limit, nil))
+	}
+
+	start := time.Now()
+	err := classifyError(checker.Check(ctx))
+	return newResourceResult(spec, waitStats{Attempts: 1, Duration: time.Since(start)}, err)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+type checkTimeoutKey struct{}
+
+// withCheckTimeout makes every check run with ctx take at most d, instead of
+// --timeout. A non-positive d keeps --timeout.
+func withCheckTimeout(ctx context.Context, d time.Duration) context.Context {
+	if d <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, checkTimeoutKey{}, d)
+}
+
+// checkTimeout is the time a single check may take: the resource's own
+// timeout when one was set with withCheckTimeout, or --timeout.
+func checkTimeout(ctx context.Context) time.Duration {
+	if d, ok := ctx.Value(checkTimeoutKey{}).(time.Duration); ok {
+		return d
+	}
+	return time.Second * time.Duration(*timeout)
+}
+
+// cappedCheck runs one check bounded by checkTimeout, even if the checker does
+// not cap itself.
+func cappedCheck(ctx context.Context, checker ResourceChecker) error {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout(ctx))
+	defer cancel()
+	return checker.Check(ctx)
+}
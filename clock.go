@@ -0,0 +1,22 @@
+package main
+
+import "time"
+
+// Clock abstracts the passage of time for the wait loops so tests can drive
+// them without sleeping.
+type Clock interface {
+	After(d time.Duration) <-chan time.Time
+	Now() time.Time
+}
+
+type realClock struct{}
+
+var _ Clock = realClock{}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+)
+
+var (
+	crdbCheckLiveness = flag.Bool("crdb-check-liveness", false, "Also require cockroach:// nodes to be live according to node liveness")
+)
+
+func isCockroachResource(resource string) bool {
+	return strings.HasPrefix(resource, "cockroach://") || strings.HasPrefix(resource, "cockroachdb://")
+}
+
+// cockroachConnString converts a cockroach:// URL into a Postgres one,
+// defaulting to CockroachDB's SQL port.
+func cockroachConnString(resource string) (string, error) {
+	u, err := url.Parse(resource)
+	if err != nil {
+		return "", newConfigError(errors.Wrap(err, "failed to parse cockroach url"))
+	}
+	u.Scheme = "postgresql"
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), "26257")
+	}
+	return u.String(), nil
+}
+
+// checkCockroachResource goes beyond SELECT 1: a node that is still joining
+// accepts SQL connections before it can read cluster settings.
+func checkCockroachResource(ctx context.Context, resource string) error {
+	cappedCtx, cancel := context.WithTimeout(ctx, checkTimeout(ctx))
+	defer cancel()
+
+	connString, err := cockroachConnString(resource)
+	if err != nil {
+		return err
+	}
+
+	pgConn, err := connectPostgres(cappedCtx, connString)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = pgConn.Close(cappedCtx)
+	}()
+
+	return checkCockroachConn(cappedCtx, pgConn)
+}
+
+// checkCockroachConn runs the cluster checks on an established connection.
+func checkCockroachConn(ctx context.Context, pgConn *pgx.Conn) error {
+	queryCtx, cancelQuery := postgresQueryContext(ctx)
+	defer cancelQuery()
+
+	var version string
+	err := pgConn.QueryRow(queryCtx, "SHOW CLUSTER SETTING version").Scan(&version)
+	if err != nil {
+		return wrapPostgresQueryError(queryCtx, err)
+	}
+	logVerbose("cockroach cluster version is %s", version)
+
+	if *dbMinVersion != "" {
+		if err := checkPostgresVersion(queryCtx, pgConn); err != nil {
+			return err
+		}
+	}
+
+	if *crdbCheckLiveness {
+		var live bool
+		err = pgConn.QueryRow(queryCtx, "SELECT is_live FROM crdb_internal.gossip_liveness WHERE node_id = crdb_internal.node_id()").Scan(&live)
+		if err != nil {
+			return wrapPostgresQueryError(queryCtx, err)
+		}
+		if !live {
+			return errors.New("cockroach node is not live yet")
+		}
+	}
+
+	return nil
+}
+
+type CockroachChecker struct {
+	ConnString string
+
+	conn *pgx.Conn
+}
+
+var _ ResourceChecker = (*CockroachChecker)(nil)
+
+func (c *CockroachChecker) Check(ctx context.Context) error {
+	if !*reuseConnection {
+		return checkCockroachResource(ctx, c.ConnString)
+	}
+
+	cappedCtx, cancel := context.WithTimeout(ctx, checkTimeout(ctx))
+	defer cancel()
+
+	if c.conn == nil {
+		connString, err := cockroachConnString(c.ConnString)
+		if err != nil {
+			return err
+		}
+		conn, err := connectPostgres(cappedCtx, connString)
+		if err != nil {
+			return err
+		}
+		c.conn = conn
+	}
+	// Like for Postgres, a failure drops the connection.
+	if err := checkCockroachConn(cappedCtx, c.conn); err != nil {
+		_ = c.Close()
+		return err
+	}
+	return nil
+}
+
+// Close releases the connection kept with --reuse-connection.
+func (c *CockroachChecker) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := c.conn.Close(ctx)
+	c.conn = nil
+	return err
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+)
+
+// version is set at build time with -ldflags "-X main.version=v1.2.3".
+var version = ""
+
+// subcommands maps the first argument to its handler. Any other first
+// argument starts an implicit "wait", so "awfi <resource>" keeps working.
+var subcommands = map[string]func(args []string){
+	"wait":       runWait,
+	"check":      runCheck,
+	"validate":   runValidate,
+	"version":    runVersion,
+	"completion": runCompletionCommand,
+}
+
+// awfiVersion falls back to the module version for binaries built with
+// go install, and to "dev" for local builds.
+func awfiVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
+func runVersion(args []string) {
+	fmt.Println("awfi", awfiVersion())
+}
+
+func runCompletionCommand(args []string) {
+	// Errors go to stderr, so a failure is not sourced as part of the script.
+	if err := runCompletion(os.Stdout, args); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitConfigError)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// resourceSchemes are offered when completing a resource argument.
+var resourceSchemes = []string{
+	"http://", "https://",
+	"postgres://", "postgresql://",
+	"cockroach://", "cockroachdb://",
+	"mysql://", "sqlserver://", "clickhouse://", "sqlite://",
+	"vault://", "vaults://",
+	"influxdb://", "influxdbs://",
+	"prometheus://", "prometheuss://",
+	"docker://",
+	"k8s://",
+	"ftp://", "ftps://",
+	"ssh://",
+	"zk://",
+	"redis://", "rediss://",
+	"mongodb://",
+	"kafka://", "kafkas://",
+	"grpc://", "grpcs://",
+}
+
+// runCompletion handles "awfi completion <shell>", writing a completion script
+// for the given shell to w.
+func runCompletion(w io.Writer, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: awfi completion bash|zsh|fish")
+	}
+	switch args[0] {
+	case "bash":
+		writeBashCompletion(w)
+	case "zsh":
+		writeZshCompletion(w)
+	case "fish":
+		writeFishCompletion(w)
+	default:
+		return errors.Errorf("unsupported shell %q, expected bash, zsh, or fish", args[0])
+	}
+	return nil
+}
+
+// completionFlags lists every registered flag as --name.
+func completionFlags() []string {
+	var names []string
+	flag.VisitAll(func(f *flag.Flag) {
+		names = append(names, "--"+f.Name)
+	})
+	return names
+}
+
+func writeBashCompletion(w io.Writer) {
+	_, _ = fmt.Fprintf(w, `# bash completion for awfi, load with: source <(awfi completion bash)
+_awfi() {
+	local cur="${COMP_WORDS[COMP_CWORD]}"
+	if [[ $COMP_CWORD -eq 2 && "${COMP_WORDS[1]}" == completion ]]; then
+		COMPREPLY=($(compgen -W "bash zsh fish" -- "$cur"))
+		return
+	fi
+	case "$cur" in
+	-*)
+		COMPREPLY=($(compgen -W "%s" -- "$cur"))
+		;;
+	*)
+		local words="%s"
+		if [[ $COMP_CWORD -eq 1 ]]; then
+			words="wait check validate version completion $words"
+		fi
+		COMPREPLY=($(compgen -W "$words" -- "$cur"))
+		compopt -o nospace
+		;;
+	esac
+}
+complete -F _awfi awfi
+`, strings.Join(completionFlags(), " "), strings.Join(resourceSchemes, " "))
+}
+
+func writeZshCompletion(w io.Writer) {
+	_, _ = fmt.Fprintf(w, `#compdef awfi
+# zsh completion for awfi, load with: source <(awfi completion zsh)
+_awfi() {
+	if (( CURRENT == 3 )) && [[ $words[2] == completion ]]; then
+		compadd -- bash zsh fish
+	elif [[ $PREFIX == -* ]]; then
+		compadd -- %s
+	else
+		(( CURRENT == 2 )) && compadd -- wait check validate version completion
+		compadd -S '' -- %s
+	fi
+}
+compdef _awfi awfi
+`, strings.Join(completionFlags(), " "), strings.Join(resourceSchemes, " "))
+}
+
+func writeFishCompletion(w io.Writer) {
+	_, _ = fmt.Fprintln(w, "# fish completion for awfi, load with: awfi completion fish | source")
+	_, _ = fmt.Fprintln(w, "complete -c awfi -f")
+	_, _ = fmt.Fprintln(w, "complete -c awfi -n '__fish_is_first_arg' -a wait -d 'Wait for resources to become available (default)'")
+	_, _ = fmt.Fprintln(w, "complete -c awfi -n '__fish_is_first_arg' -a check -d 'Check resources once without retrying'")
+	_, _ = fmt.Fprintln(w, "complete -c awfi -n '__fish_is_first_arg' -a validate -d 'Check manifest, resources, and groups files without running checks'")
+	_, _ = fmt.Fprintln(w, "complete -c awfi -n '__fish_is_first_arg' -a version -d 'Print the awfi version'")
+	_, _ = fmt.Fprintln(w, "complete -c awfi -n '__fish_is_first_arg' -a completion -d 'Print a shell completion script'")
+	_, _ = fmt.Fprintln(w, "complete -c awfi -n '__fish_seen_subcommand_from completion' -a 'bash zsh fish'")
+	flag.VisitAll(func(f *flag.Flag) {
+		line := fmt.Sprintf("complete -c awfi -l %s -d %s", f.Name, fishQuote(f.Usage))
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+			line += " -r"
+		}
+		_, _ = fmt.Fprintln(w, line)
+	})
+	_, _ = fmt.Fprintf(w, "complete -c awfi -n 'not __fish_seen_subcommand_from completion' -a %s\n", fishQuote(strings.Join(resourceSchemes, " ")))
+}
+
+// fishQuote single-quotes s for fish, which only treats \\ and \' specially
+// inside single quotes.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
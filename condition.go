@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// condition mirrors the fields of a Kubernetes metav1.Condition, with the
+// resource it describes, for --output=k8s-condition.
+type condition struct {
+	Type               string `json:"type"`
+	Resource           string `json:"resource"`
+	Status             string `json:"status"`
+	Reason             string `json:"reason"`
+	Message            string `json:"message"`
+	LastTransitionTime string `json:"lastTransitionTime"`
+}
+
+// newCondition describes a result as a Ready condition. Reasons are
+// CamelCase, as Kubernetes requires.
+func newCondition(result resourceResult, now time.Time) condition {
+	c := condition{
+		Type:               "Ready",
+		Resource:           result.Name,
+		LastTransitionTime: now.UTC().Format(time.RFC3339),
+	}
+	var giveUp *giveUpError
+	switch {
+	case result.Skipped:
+		c.Status, c.Reason = "Unknown", "Skipped"
+		c.Message = "skipped because its group was already decided"
+	case result.Ready:
+		c.Status, c.Reason = "True", "ResourceReady"
+		c.Message = "became ready after " + result.Duration.Round(time.Millisecond).String()
+	case errors.As(result.Err, &giveUp):
+		c.Status, c.Reason = "False", "TimedOut"
+		c.Message = result.Error
+	case isConfigError(result.Err):
+		c.Status, c.Reason = "False", "ConfigurationError"
+		c.Message = result.Error
+	default:
+		c.Status, c.Reason = "False", "CheckFailed"
+		c.Message = result.Error
+	}
+	return c
+}
+
+// writeConditions prints one condition per resource as a JSON array.
+func writeConditions(w io.Writer, results []resourceResult) {
+	now := time.Now()
+	conditions := make([]condition, 0, len(results))
+	for _, result := range results {
+		conditions = append(conditions, newCondition(result, now))
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(conditions)
+}
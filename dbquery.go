@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"time"
+)
+
+var (
+	dbQueryTimeout = flag.Duration("db-query-timeout", 0, "Deadline for the readiness query of Postgres, CockroachDB, and SQL databases, separate from the connect timeout (0 to use the attempt's)")
+)
+
+// dbQueryContext bounds a readiness query by --db-query-timeout.
+func dbQueryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if *dbQueryTimeout > 0 {
+		return context.WithTimeout(ctx, *dbQueryTimeout)
+	}
+	return ctx, func() {}
+}
+
+// isDbQueryTimeout reports whether a query failed because queryCtx, rather
+// than the attempt's ctx, ran out.
+func isDbQueryTimeout(ctx, queryCtx context.Context) bool {
+	return queryCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil
+}
+
+// shorterTimeout returns the smaller of two timeouts, where zero means none.
+func shorterTimeout(a, b time.Duration) time.Duration {
+	if a == 0 || (b > 0 && b < a) {
+		return b
+	}
+	return a
+}
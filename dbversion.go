@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	dbMinVersion = flag.String("db-min-version", "", "Require Postgres, CockroachDB, and SQL databases to report at least this version, e.g. 14 or 8.0.32")
+)
+
+var dbVersionPattern = regexp.MustCompile(`\d+(\.\d+)*`)
+
+// parseDbVersion extracts the first dotted number from a version string, so
+// vendor decorations such as "PostgreSQL 16.2 on x86_64-pc-linux-gnu",
+// "10.11.6-MariaDB-1:10.11.6+maria~ubu2204", or "CockroachDB CCL v23.1.11"
+// are ignored.
+func parseDbVersion(s string) ([]int, error) {
+	match := dbVersionPattern.FindString(s)
+	if match == "" {
+		return nil, errors.Errorf("no version number in %q", s)
+	}
+	var version []int
+	for _, part := range strings.Split(match, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid version number in %q", s)
+		}
+		version = append(version, n)
+	}
+	return version, nil
+}
+
+// compareDbVersions orders versions component by component, treating missing
+// components as zero.
+func compareDbVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func validateDbFlags() error {
+	if *dbQueryTimeout < 0 {
+		return errors.Errorf("invalid --db-query-timeout %s, must not be negative", *dbQueryTimeout)
+	}
+	if *dbMinVersion == "" {
+		return nil
+	}
+	if _, err := parseDbVersion(*dbMinVersion); err != nil || strings.TrimLeft(*dbMinVersion, "0123456789.") != "" {
+		return errors.Errorf("invalid --db-min-version %q, expected a version such as 14 or 8.0.32", *dbMinVersion)
+	}
+	return nil
+}
+
+// checkDbVersion compares the version string reported by a server against
+// --db-min-version.
+func checkDbVersion(reported string) error {
+	logVerbose("database server version is %s", reported)
+	version, err := parseDbVersion(reported)
+	if err != nil {
+		return newConfigError(errors.Wrap(err, "failed to parse database server version"))
+	}
+	min, _ := parseDbVersion(*dbMinVersion)
+	if compareDbVersions(version, min) < 0 {
+		return errors.Errorf("database server version %s is older than the required %s", dbVersionPattern.FindString(reported), *dbMinVersion)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	deadline = flag.String("deadline", "", "Keep waiting until this RFC3339 time, e.g. 2026-01-02T09:00:00+01:00, instead of for --timeout seconds (each attempt is still capped by --timeout)")
+)
+
+// deadlineAt is the parsed --deadline, zero when unset.
+var deadlineAt time.Time
+
+// validateDeadlineFlag parses --deadline, which must lie in the future.
+func validateDeadlineFlag() error {
+	if *deadline == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, *deadline)
+	if err != nil {
+		return errors.Wrap(err, "invalid --deadline, expected an RFC3339 time such as 2026-01-02T09:00:00Z")
+	}
+	if !t.After(time.Now()) {
+		return errors.Errorf("--deadline %s is already in the past", *deadline)
+	}
+	deadlineAt = t
+	return nil
+}
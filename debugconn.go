@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+)
+
+var (
+	debugConnection = flag.Bool("debug-connection", false, "Log the address, TLS parameters, and server version of every connection a check makes to stderr")
+)
+
+// logConnection writes connection details for --debug-connection. They are
+// logged regardless of --verbose, and never affect the outcome of a check.
+// Callers must redact resources themselves.
+func logConnection(format string, args ...interface{}) {
+	if !*debugConnection {
+		return
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "connection: "+format+"\n", args...)
+}
+
+// withConnectionTrace logs the connections made for requests of resource,
+// including those that fail.
+func withConnectionTrace(req *http.Request, resource string) *http.Request {
+	if !*debugConnection {
+		return req
+	}
+	name := redactResource(resource)
+	trace := &httptrace.ClientTrace{
+		ConnectDone: func(network, addr string, err error) {
+			if err != nil {
+				logConnection("%s: failed to connect to %s: %v", name, addr, err)
+				return
+			}
+			logConnection("%s: connected to %s", name, addr)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				logConnection("%s: reusing connection to %s", name, info.Conn.RemoteAddr())
+			}
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			if err != nil {
+				logConnection("%s: tls handshake failed: %v", name, err)
+				return
+			}
+			logConnection("%s: %s", name, describeTLS(&state))
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
+
+func describeTLS(state *tls.ConnectionState) string {
+	desc := fmt.Sprintf("%s, %s, server name %q", tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite), state.ServerName)
+	if state.NegotiatedProtocol != "" {
+		desc += ", protocol " + state.NegotiatedProtocol
+	}
+	if len(state.PeerCertificates) > 0 {
+		desc += fmt.Sprintf(", certificate %q issued by %q", state.PeerCertificates[0].Subject.CommonName, state.PeerCertificates[0].Issuer.CommonName)
+	}
+	return desc
+}
+
+// logSqlServerVersion queries the server version for --debug-connection.
+// A failing query is logged too, but not returned.
+func logSqlServerVersion(ctx context.Context, db *sql.DB, driver sqlDriver, resource string) {
+	if !*debugConnection || driver.VersionQuery == "" {
+		return
+	}
+	var version string
+	if err := db.QueryRowContext(ctx, driver.VersionQuery).Scan(&version); err != nil {
+		logConnection("%s: failed to query server version: %v", redactResource(resource), err)
+		return
+	}
+	logConnection("%s: server version %s", redactResource(resource), version)
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/proxy"
+)
+
+var (
+	socks5Proxy  = flag.String("socks5", "", "SOCKS5 proxy, as [user:password@]host:port, used by TCP-based and HTTP checks")
+	resolverAddr = flag.String("resolver", "", "DNS server, as host:port, used instead of the system resolver by every check")
+	tcpNoLinger  = flag.Bool("tcp-no-linger", false, "Reset connections of the FTP, SSH, Zookeeper, Redis, and MongoDB checks when closing them, instead of a graceful close that leaves sockets in TIME_WAIT")
+	localAddr    = flag.String("local-addr", "", "Local IP address, or network interface to take the first address of, that TCP-based and HTTP checks connect from")
+
+	connectTimeout = flag.Duration("connect-timeout", 0, "Time the FTP, SSH, Zookeeper, Redis, and MongoDB checks may take to establish the TCP connection (0 to use the attempt's timeout)")
+	readTimeout    = flag.Duration("read-timeout", 0, "Time the FTP, SSH, Zookeeper, Redis, and MongoDB checks may take to speak their protocol once connected (0 to use the attempt's timeout)")
+)
+
+func validateTimeoutFlags() error {
+	if *connectTimeout < 0 || *readTimeout < 0 {
+		return errors.New("--connect-timeout and --read-timeout must not be negative")
+	}
+	return nil
+}
+
+// localTCPAddr is --local-addr resolved at startup.
+var localTCPAddr *net.TCPAddr
+
+// validateLocalAddrFlag resolves --local-addr and makes sure a socket can be
+// bound to it, so a missing address fails at startup instead of on every
+// attempt.
+func validateLocalAddrFlag() error {
+	if *localAddr == "" {
+		return nil
+	}
+	ip := net.ParseIP(*localAddr)
+	if ip == nil {
+		iface, err := net.InterfaceByName(*localAddr)
+		if err != nil {
+			return errors.Errorf("invalid --local-addr %s, expected an IP address or a network interface", *localAddr)
+		}
+		ip, err = interfaceIP(iface)
+		if err != nil {
+			return errors.Wrapf(err, "invalid --local-addr %s", *localAddr)
+		}
+	}
+	addr := &net.TCPAddr{IP: ip}
+	l, err := net.ListenTCP("tcp", addr)
+	if err != nil {
+		return errors.Wrapf(err, "cannot bind --local-addr %s", *localAddr)
+	}
+	_ = l.Close()
+	localTCPAddr = addr
+	return nil
+}
+
+// interfaceIP returns the first IPv4 address of iface, or its first IPv6
+// address when it has none.
+func interfaceIP(iface *net.Interface) (net.IP, error) {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list the addresses of %s", iface.Name)
+	}
+	var first net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ip4 := ipNet.IP.To4(); ip4 != nil {
+			return ip4, nil
+		}
+		if first == nil {
+			first = ipNet.IP
+		}
+	}
+	if first == nil {
+		return nil, errors.Errorf("%s has no IP address", iface.Name)
+	}
+	return first, nil
+}
+
+// newDialer returns a dialer connecting from --local-addr when set.
+func newDialer() *net.Dialer {
+	d := &net.Dialer{}
+	if localTCPAddr != nil {
+		d.LocalAddr = localTCPAddr
+	}
+	return d
+}
+
+// socks5Dialer parses --socks5. Host names are resolved by the proxy, so
+// resources only known on the far side of it can be reached.
+func socks5Dialer(spec string) (proxy.ContextDialer, error) {
+	var auth *proxy.Auth
+	address := spec
+	if at := strings.LastIndex(spec, "@"); at != -1 {
+		user, password, _ := strings.Cut(spec[:at], ":")
+		auth = &proxy.Auth{User: user, Password: password}
+		address = spec[at+1:]
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return nil, errors.Wrap(err, "invalid --socks5 address")
+	}
+	d, err := proxy.SOCKS5("tcp", address, auth, newDialer())
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid --socks5 proxy")
+	}
+	return d.(proxy.ContextDialer), nil
+}
+
+func validateResolverFlag() error {
+	if *resolverAddr == "" {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(*resolverAddr); err != nil {
+		return errors.Wrap(err, "invalid --resolver address")
+	}
+	return nil
+}
+
+// resolver returns a resolver querying the --resolver server, or the system
+// resolver when it is unset. Lookups are canceled with their context.
+func resolver() *net.Resolver {
+	if *resolverAddr == "" {
+		return net.DefaultResolver
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, *resolverAddr)
+		},
+	}
+}
+
+type pinnedHostKey struct{}
+
+type pinnedHost struct {
+	host, ip string
+}
+
+// withPinnedHost makes dialContext connect to ip whenever it dials host, so a
+// single address behind a name can be checked while TLS and the Host header
+// still use the name.
+func withPinnedHost(ctx context.Context, host, ip string) context.Context {
+	return context.WithValue(ctx, pinnedHostKey{}, pinnedHost{host: host, ip: ip})
+}
+
+func isPinned(ctx context.Context) bool {
+	_, ok := ctx.Value(pinnedHostKey{}).(pinnedHost)
+	return ok
+}
+
+// dialContext connects directly, or through the --socks5 proxy when set. The
+// proxied dial, including the SOCKS handshake, is canceled with ctx. Direct
+// dials resolve names with resolver; the proxy resolves them itself. Either
+// way the connection is made from --local-addr.
+func dialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if pin, ok := ctx.Value(pinnedHostKey{}).(pinnedHost); ok {
+		if host, port, err := net.SplitHostPort(address); err == nil && host == pin.host {
+			address = net.JoinHostPort(pin.ip, port)
+		}
+	}
+	if *socks5Proxy == "" {
+		d := newDialer()
+		d.Resolver = resolver()
+		return d.DialContext(ctx, network, address)
+	}
+	// The flag is validated at startup.
+	d, _ := socks5Dialer(*socks5Proxy)
+	return d.DialContext(ctx, network, address)
+}
+
+// dialTCP opens a TCP connection for the raw protocol checkers. The dial is
+// bounded by --connect-timeout, and the returned connection carries a deadline
+// --read-timeout from now, so a server that accepts connections but stalls on
+// the protocol is told apart from one that cannot be reached. Both are capped
+// by the context's deadline.
+func dialTCP(ctx context.Context, address string) (net.Conn, error) {
+	dialCtx := ctx
+	if *connectTimeout > 0 {
+		var cancel context.CancelFunc
+		dialCtx, cancel = context.WithTimeout(ctx, *connectTimeout)
+		defer cancel()
+	}
+	conn, err := dialContext(dialCtx, "tcp", address)
+	if err != nil {
+		if ctx.Err() == nil && dialCtx.Err() == context.DeadlineExceeded {
+			return nil, errors.Wrapf(err, "failed to connect within --connect-timeout %s", *connectTimeout)
+		}
+		return nil, errors.Wrap(err, "failed to connect")
+	}
+	logConnection("%s: connected to %s from %s", address, conn.RemoteAddr(), conn.LocalAddr())
+
+	if *tcpNoLinger {
+		// Through --socks5 this is the connection to the proxy.
+		if tcpConn, ok := conn.(*net.TCPConn); ok {
+			_ = tcpConn.SetLinger(0)
+		}
+	}
+
+	_ = conn.SetDeadline(protocolDeadline(ctx))
+
+	return conn, nil
+}
+
+// protocolDeadline is the deadline of ctx, or --read-timeout from now when
+// that is sooner. Connections kept across attempts are given a new one for
+// every attempt.
+func protocolDeadline(ctx context.Context) time.Time {
+	deadline, _ := ctx.Deadline()
+	if *readTimeout > 0 {
+		if readDeadline := time.Now().Add(*readTimeout); deadline.IsZero() || readDeadline.Before(deadline) {
+			deadline = readDeadline
+		}
+	}
+	return deadline
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const defaultDockerHost = "unix:///var/run/docker.sock"
+
+var (
+	dockerHost          = flag.String("docker-host", "", "Docker daemon address for docker:// resources (defaults to $DOCKER_HOST or "+defaultDockerHost+")")
+	dockerRequireHealth = flag.Bool("docker-require-healthcheck", false, "Fail docker:// resources that do not define a healthcheck instead of accepting a running container")
+)
+
+func isDockerResource(resource string) bool {
+	return strings.HasPrefix(resource, "docker://")
+}
+
+func effectiveDockerHost() string {
+	if *dockerHost != "" {
+		return *dockerHost
+	}
+	if env := os.Getenv("DOCKER_HOST"); env != "" {
+		return env
+	}
+	return defaultDockerHost
+}
+
+// newDockerClient returns an HTTP client that talks to the daemon along with
+// the base URL requests should be made against.
+func newDockerClient(host string) (*http.Client, string, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to parse docker host")
+	}
+
+	transport := newHttpTransport()
+	switch u.Scheme {
+	case "unix":
+		socketPath := u.Path
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socketPath)
+		}
+		// The host portion is ignored when dialing a unix socket.
+		return newHttpClient(transport), "http://docker", nil
+	case "tcp", "http":
+		return newHttpClient(transport), "http://" + u.Host, nil
+	case "https":
+		return newHttpClient(transport), "https://" + u.Host, nil
+	default:
+		return nil, "", errors.Errorf("unsupported docker host scheme: %s", u.Scheme)
+	}
+}
+
+func checkDockerResource(ctx context.Context, cx *http.Client, baseURL string, resource string) error {
+	cappedCtx, cancel := context.WithTimeout(ctx, checkTimeout(ctx))
+	defer cancel()
+
+	container := strings.TrimPrefix(resource, "docker://")
+	if container == "" {
+		return newConfigError(errors.New("container name is required"))
+	}
+
+	req, err := http.NewRequestWithContext(cappedCtx, "GET", baseURL+"/containers/"+url.PathEscape(container)+"/json", nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+
+	resp, err := cx.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to query docker daemon")
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode == http.StatusNotFound {
+		// Compose may not have created the container yet, keep waiting.
+		return errors.Errorf("container %s not found", container)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status code %d inspecting container", resp.StatusCode)
+	}
+
+	var inspect struct {
+		State struct {
+			Status  string `json:"Status"`
+			Running bool   `json:"Running"`
+			Health  *struct {
+				Status string `json:"Status"`
+			} `json:"Health"`
+		} `json:"State"`
+	}
+	body, err := decodedBody(resp)
+	if err != nil {
+		return err
+	}
+	if err := json.NewDecoder(body).Decode(&inspect); err != nil {
+		return errors.Wrap(err, "failed to decode container inspect response")
+	}
+
+	if inspect.State.Health == nil {
+		if *dockerRequireHealth {
+			return newConfigError(errors.Errorf("container %s does not define a healthcheck", container))
+		}
+		if !inspect.State.Running {
+			return errors.Errorf("container %s is %s", container, inspect.State.Status)
+		}
+		return nil
+	}
+
+	if inspect.State.Health.Status != "healthy" {
+		return errors.Errorf("container %s is %s", container, inspect.State.Health.Status)
+	}
+
+	return nil
+}
+
+type DockerChecker struct {
+	Resource string
+
+	// client and baseURL are kept across attempts so connections can be
+	// reused.
+	client  *http.Client
+	baseURL string
+}
+
+var _ ResourceChecker = (*DockerChecker)(nil)
+
+func (d *DockerChecker) Check(ctx context.Context) error {
+	if d.client == nil {
+		cx, baseURL, err := newDockerClient(effectiveDockerHost())
+		if err != nil {
+			return newConfigError(err)
+		}
+		d.client, d.baseURL = cx, baseURL
+	}
+	return checkDockerResource(ctx, d.client, d.baseURL, d.Resource)
+}
+
+// Close releases the connections kept between attempts.
+func (d *DockerChecker) Close() error {
+	if d.client != nil {
+		d.client.CloseIdleConnections()
+	}
+	return nil
+}
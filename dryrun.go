@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+var (
+	dryRun = flag.Bool("dry-run", false, "Print how each resource would be checked and exit without checking anything")
+)
+
+type dryRunEntry struct {
+	Name     string `json:"name"`
+	Scheme   string `json:"scheme"`
+	Checker  string `json:"checker"`
+	Timeout  string `json:"timeout"`
+	Interval string `json:"interval"`
+	Target   string `json:"target"`
+}
+
+// checkerName returns the checker's type name, or "unsupported" for a nil
+// checker.
+func checkerName(checker ResourceChecker) string {
+	if checker == nil {
+		return "unsupported"
+	}
+	name := fmt.Sprintf("%T", checker)
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+func printDryRun(specs []resourceSpec) {
+	entries := make([]dryRunEntry, 0, len(specs))
+	for _, spec := range specs {
+		entries = append(entries, dryRunEntry{
+			Name:     spec.Name,
+			Scheme:   resourceScheme(spec.Resource),
+			Checker:  checkerName(spec.checker()),
+			Timeout:  spec.waitTimeout().Round(time.Millisecond).String(),
+			Interval: spec.interval().String(),
+			Target:   redactResource(spec.Resource),
+		})
+	}
+
+	if *outputFormat == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(entries)
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "NAME\tSCHEME\tCHECKER\tTIMEOUT\tINTERVAL\tTARGET")
+	for _, e := range entries {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", e.Name, e.Scheme, e.Checker, e.Timeout, e.Interval, e.Target)
+	}
+	_ = tw.Flush()
+}
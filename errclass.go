@@ -0,0 +1,139 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"strings"
+
+	"github.com/jackc/pgconn"
+	"github.com/pkg/errors"
+)
+
+// Errors returned by waitForResource and the checkers match these categories
+// with errors.Is, which decide the exit status (see categoryExitCode):
+//
+//   - ErrTimeout: the wait ended before the resource became available. The
+//     last check error is wrapped too, so it may match another category.
+//   - ErrConfig: retrying will not help, e.g. an unknown host, an untrusted
+//     certificate, or a malformed resource.
+//   - ErrAuth: the resource rejected the credentials. Every ErrAuth is also
+//     an ErrConfig.
+var (
+	ErrTimeout = errors.New("timed out")
+	ErrConfig  = errors.New("configuration error")
+	ErrAuth    = errors.New("authentication failed")
+)
+
+// configError marks a failure that retrying will not fix, such as a bad
+// credential or an unparseable resource. See isConfigError.
+type configError struct {
+	err  error
+	auth bool
+}
+
+func (e *configError) Error() string { return e.err.Error() }
+func (e *configError) Unwrap() error { return e.err }
+func (e *configError) Cause() error  { return e.err }
+
+func (e *configError) Is(target error) bool {
+	return target == ErrConfig || (e.auth && target == ErrAuth)
+}
+
+func newConfigError(err error) error {
+	return &configError{err: err}
+}
+
+// newAuthError marks rejected credentials, a configuration error matching
+// ErrAuth as well.
+func newAuthError(err error) error {
+	return &configError{err: err, auth: true}
+}
+
+// fatalError marks a failure that stops the wait immediately, regardless of
+// --fail-on-config-error.
+type fatalError struct {
+	err error
+}
+
+func (e *fatalError) Error() string { return e.err.Error() }
+func (e *fatalError) Unwrap() error { return e.err }
+func (e *fatalError) Cause() error  { return e.err }
+
+func newFatalError(err error) error {
+	return &fatalError{err: err}
+}
+
+func isFatalError(err error) bool {
+	var fe *fatalError
+	return errors.As(err, &fe)
+}
+
+// isConfigError reports whether err points at a configuration problem rather
+// than a resource that is still starting. Checkers mark the failures only
+// they can recognize with newConfigError; common error types are classified
+// here:
+//
+//   - unknown hosts (NXDOMAIN, not temporary DNS failures)
+//   - certificates from an unknown authority, for the wrong host, or
+//     otherwise invalid, except expired and not yet valid ones, which are
+//     what a server mid certificate rotation presents
+//   - Postgres authentication failures (SQLSTATE 28000 and 28P01)
+//
+// Everything else, notably refused connections, timeouts, and TLS handshakes
+// that fail or are reset before a certificate is verified, is transient.
+func isConfigError(err error) bool {
+	var ce *configError
+	if errors.As(err, &ce) {
+		return true
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return true
+	}
+
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	if errors.As(err, &unknownAuthority) || errors.As(err, &hostname) {
+		return true
+	}
+	if errors.As(err, &invalid) && invalid.Reason != x509.Expired {
+		return true
+	}
+
+	return isPostgresAuthError(err)
+}
+
+// isTLSHandshakeError reports whether err happened while negotiating TLS,
+// before any certificate was verified: an alert from the server, such as one
+// that has no certificate yet, a non-TLS response, or a handshake timeout.
+func isTLSHandshakeError(err error) bool {
+	// crypto/tls reports alerts received from the server as a net.OpError;
+	// tls.AlertError is only returned to QUIC implementations.
+	var opErr *net.OpError
+	var alert tls.AlertError
+	var header tls.RecordHeaderError
+	return (errors.As(err, &opErr) && opErr.Op == "remote error") ||
+		errors.As(err, &alert) || errors.As(err, &header) ||
+		strings.Contains(err.Error(), "TLS handshake timeout")
+}
+
+func isPostgresAuthError(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (pgErr.Code == "28000" || pgErr.Code == "28P01")
+}
+
+// classifyError wraps the failures isConfigError recognizes by their type in a
+// configError, so they match ErrConfig (and ErrAuth) with errors.Is.
+func classifyError(err error) error {
+	var ce *configError
+	if err == nil || errors.As(err, &ce) || !isConfigError(err) {
+		return err
+	}
+	if isPostgresAuthError(err) {
+		return newAuthError(err)
+	}
+	return newConfigError(err)
+}
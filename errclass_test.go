@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestIsTLSHandshakeError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"remote alert", errors.Wrap(&net.OpError{Op: "remote error", Err: errors.New("tls: internal error")}, "Get \"https://example.com\""), true},
+		{"quic alert", tls.AlertError(40), true},
+		{"record header", tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}, true},
+		{"handshake timeout", errors.New("net/http: TLS handshake timeout"), true},
+		{"wrapped handshake timeout", errors.Wrap(errors.New("net/http: TLS handshake timeout"), "Get \"https://example.com\""), true},
+		{"connection refused", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connect: connection refused")}, false},
+		{"other timeout", errors.New("context deadline exceeded"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTLSHandshakeError(tt.err); got != tt.want {
+				t.Errorf("isTLSHandshakeError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestTLSHandshakeTimeoutIsRetried makes sure the error net/http returns for
+// a server that accepts connections but never answers the handshake still
+// matches, and is not a configuration error.
+func TestTLSHandshakeTimeoutIsRetried(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	transport := newHttpCheckTransport()
+	transport.TLSHandshakeTimeout = 50 * time.Millisecond
+	defer transport.CloseIdleConnections()
+	err = checkHttpResource(context.Background(), transport, "https://"+ln.Addr().String()+"/", "", nil, nil)
+	if err == nil {
+		t.Fatal("checkHttpResource() = nil, want a handshake timeout")
+	}
+	if !isTLSHandshakeError(err) {
+		t.Errorf("isTLSHandshakeError(%v) = false, want true", err)
+	}
+	if isConfigError(classifyError(err)) {
+		t.Errorf("%v classified as a configuration error", err)
+	}
+}
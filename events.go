@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	eventsFile = flag.String("events-file", "", "Append every attempt and outcome as newline-delimited JSON to this file")
+)
+
+// event is a single line of the --events-file.
+type event struct {
+	Time      time.Time `json:"time"`
+	Resource  string    `json:"resource"`
+	Type      string    `json:"type"`
+	Attempt   int       `json:"attempt"`
+	LatencyMs float64   `json:"latency_ms,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// eventsWriter buffers events in memory so that concurrent waits only
+// contend on a lock, not on the disk. The buffer is flushed by Close.
+type eventsWriter struct {
+	mu  sync.Mutex
+	f   *os.File
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func openEventsFile(path string) (*eventsWriter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open events file")
+	}
+	buf := bufio.NewWriter(f)
+	return &eventsWriter{f: f, buf: buf, enc: json.NewEncoder(buf)}, nil
+}
+
+func eventType(result CheckResult) string {
+	switch {
+	case result.Done && result.Err == nil:
+		return "ready"
+	case result.Done:
+		return "not_ready"
+	case result.Err == nil:
+		return "attempt_succeeded"
+	default:
+		return "attempt_failed"
+	}
+}
+
+func (w *eventsWriter) record(resource string, result CheckResult) {
+	e := event{
+		Time:      time.Now().UTC(),
+		Resource:  resource,
+		Type:      eventType(result),
+		Attempt:   result.Attempt,
+		LatencyMs: float64(result.Latency) / float64(time.Millisecond),
+	}
+	if result.Err != nil {
+		e.Error = result.Err.Error()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_ = w.enc.Encode(e)
+}
+
+func (w *eventsWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.buf.Flush(); err != nil {
+		_ = w.f.Close()
+		return errors.Wrap(err, "failed to write events file")
+	}
+	return errors.Wrap(w.f.Close(), "failed to close events file")
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	exitCount = flag.Bool("exit-count", false, "On failure, exit with the number of unready resources (at most 125) instead of their error category")
+)
+
+// maxExitCount keeps the count clear of the codes shells reserve for commands
+// that cannot run (126, 127) and for signals (128 and up).
+const maxExitCount = 125
+
+// Exit statuses of a failed run, by the error category of its resources.
+const (
+	exitFailed      = 1
+	exitConfigError = 2
+	exitAuthError   = 3
+)
+
+// failureExitCode is the exit status of a run that did not become ready.
+func failureExitCode(results []resourceResult) int {
+	if !*exitCount {
+		code := exitFailed
+		for _, result := range results {
+			if !result.Ready && !result.Skipped {
+				code = max(code, categoryExitCode(result.Err))
+			}
+		}
+		return code
+	}
+	unready := 0
+	for _, result := range results {
+		if !result.Ready && !result.Skipped {
+			unready++
+		}
+	}
+	switch {
+	case unready == 0:
+		return 1
+	case unready > maxExitCount:
+		return maxExitCount
+	default:
+		return unready
+	}
+}
+
+// categoryExitCode maps err to an exit status. A wait that timed out exits
+// with 1 whatever its last error was, as only --fail-on-config-error and
+// check stop at a configuration error.
+func categoryExitCode(err error) int {
+	switch {
+	case errors.Is(err, ErrTimeout):
+		return exitFailed
+	case errors.Is(err, ErrAuth):
+		return exitAuthError
+	case errors.Is(err, ErrConfig):
+		return exitConfigError
+	default:
+		return exitFailed
+	}
+}
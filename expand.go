@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// maxExpansions keeps a typo such as [1-100000] from starting that many waits.
+const maxExpansions = 1000
+
+var rangePattern = regexp.MustCompile(`^\[(\d+)-(\d+)\]`)
+
+// expansion is one resource produced by expandResource, along with the values
+// substituted into it.
+type expansion struct {
+	Resource string
+	Values   []string
+}
+
+// expandResource expands numeric ranges such as [1-3] (zero-padded as in
+// [01-10]) and comma lists such as {a,b} in resource into every combination,
+// in order. Brackets that do not hold a range, like those of IPv6 addresses,
+// and braces without a comma are kept. So are brackets and braces escaped
+// with a backslash, which is removed.
+func expandResource(resource string) ([]expansion, error) {
+	expansions, err := expandFrom(resource, 0)
+	if err != nil {
+		return nil, err
+	}
+	for i := range expansions {
+		expansions[i].Resource = unescapeBrackets(expansions[i].Resource)
+	}
+	return expansions, nil
+}
+
+func expandFrom(s string, start int) ([]expansion, error) {
+	for i := start; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		var alternatives []string
+		var end int
+		switch s[i] {
+		case '[':
+			m := rangePattern.FindStringSubmatch(s[i:])
+			if m == nil {
+				continue
+			}
+			values, err := rangeValues(m[1], m[2])
+			if err != nil {
+				return nil, err
+			}
+			alternatives, end = values, i+len(m[0])
+		case '{':
+			close := strings.IndexByte(s[i:], '}')
+			if close == -1 || !strings.Contains(s[i:i+close], ",") {
+				continue
+			}
+			alternatives, end = strings.Split(s[i+1:i+close], ","), i+close+1
+		default:
+			continue
+		}
+
+		var expansions []expansion
+		for _, alt := range alternatives {
+			rest, err := expandFrom(s[:i]+alt+s[end:], i+len(alt))
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range rest {
+				expansions = append(expansions, expansion{Resource: e.Resource, Values: append([]string{alt}, e.Values...)})
+			}
+			if len(expansions) > maxExpansions {
+				return nil, errors.Errorf("%s expands to more than %d resources", redactResource(s), maxExpansions)
+			}
+		}
+		return expansions, nil
+	}
+	return []expansion{{Resource: s}}, nil
+}
+
+// rangeValues lists the numbers from first to last, keeping the width of a
+// zero-padded first number.
+func rangeValues(first, last string) ([]string, error) {
+	from, err := strconv.Atoi(first)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid range [%s-%s]", first, last)
+	}
+	to, err := strconv.Atoi(last)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid range [%s-%s]", first, last)
+	}
+	if to < from {
+		return nil, errors.Errorf("invalid range [%s-%s], the end is before the start", first, last)
+	}
+	if to-from >= maxExpansions {
+		return nil, errors.Errorf("range [%s-%s] expands to more than %d resources", first, last, maxExpansions)
+	}
+	format := "%d"
+	if len(first) > 1 && first[0] == '0' {
+		format = fmt.Sprintf("%%0%dd", len(first))
+	}
+	values := make([]string, 0, to-from+1)
+	for n := from; n <= to; n++ {
+		values = append(values, fmt.Sprintf(format, n))
+	}
+	return values, nil
+}
+
+func unescapeBrackets(s string) string {
+	return strings.NewReplacer(`\[`, `[`, `\]`, `]`, `\{`, `{`, `\}`, `}`).Replace(s)
+}
+
+// expandSpec expands spec's resource. Explicit names get the substituted
+// values appended, e.g. db-1 for db=postgres://db-[1-3]; otherwise each
+// resource is named after itself as usual.
+func expandSpec(spec resourceSpec) ([]resourceSpec, error) {
+	named := spec.Name != redactResource(spec.Resource)
+	expansions, err := expandResource(spec.Resource)
+	if err != nil {
+		return nil, err
+	}
+	specs := make([]resourceSpec, len(expansions))
+	for i, e := range expansions {
+		specs[i] = spec
+		specs[i].Resource = e.Resource
+		if named {
+			specs[i].Name = expandedName(spec.Name, e.Values)
+		} else {
+			specs[i].Name = redactResource(e.Resource)
+		}
+	}
+	return specs, nil
+}
+
+func expandedName(name string, values []string) string {
+	if len(values) == 0 {
+		return name
+	}
+	return name + "-" + strings.Join(values, "-")
+}
@@ -0,0 +1,16 @@
+package main
+
+import "strings"
+
+// repeatedFlag collects every occurrence of a flag that may be given more
+// than once.
+type repeatedFlag []string
+
+func (r *repeatedFlag) String() string {
+	return strings.Join(*r, ", ")
+}
+
+func (r *repeatedFlag) Set(value string) error {
+	*r = append(*r, value)
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ftpPassive = flag.Bool("ftp-passive", false, "Require ftp:// and ftps:// resources to accept a passive mode (PASV) request")
+)
+
+func isFtpResource(resource string) bool {
+	return strings.HasPrefix(resource, "ftp://") || strings.HasPrefix(resource, "ftps://")
+}
+
+// ftpCmd sends a command and expects a reply with the given status code.
+func ftpCmd(conn *textproto.Conn, expectCode int, format string, args ...interface{}) (int, string, error) {
+	id, err := conn.Cmd(format, args...)
+	if err != nil {
+		return 0, "", err
+	}
+	conn.StartResponse(id)
+	defer conn.EndResponse(id)
+	return conn.ReadResponse(expectCode)
+}
+
+// checkFtpResource reads the welcome banner and, when the URL carries
+// credentials, logs in. ftps:// upgrades the control connection with AUTH TLS
+// (explicit FTPS) before logging in.
+func checkFtpResource(ctx context.Context, resource string) error {
+	cappedCtx, cancel := context.WithTimeout(ctx, checkTimeout(ctx))
+	defer cancel()
+
+	u, err := url.Parse(resource)
+	if err != nil {
+		return newConfigError(errors.Wrap(err, "failed to parse ftp url"))
+	}
+	address := u.Host
+	if u.Port() == "" {
+		address = net.JoinHostPort(u.Hostname(), "21")
+	}
+
+	rawConn, err := dialTCP(cappedCtx, address)
+	if err != nil {
+		return err
+	}
+	stop := context.AfterFunc(cappedCtx, func() {
+		_ = rawConn.Close()
+	})
+	defer stop()
+
+	conn := textproto.NewConn(rawConn)
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if _, _, err := conn.ReadResponse(220); err != nil {
+		return errors.Wrap(err, "failed to read ftp welcome banner")
+	}
+
+	if u.Scheme == "ftps" {
+		if _, _, err := ftpCmd(conn, 234, "AUTH TLS"); err != nil {
+			return errors.Wrap(err, "ftp server refused AUTH TLS")
+		}
+		tlsConn := tls.Client(rawConn, &tls.Config{ServerName: u.Hostname()})
+		if err := tlsConn.HandshakeContext(cappedCtx); err != nil {
+			return errors.Wrap(err, "ftp tls handshake failed")
+		}
+		state := tlsConn.ConnectionState()
+		logConnection("%s: %s", address, describeTLS(&state))
+		conn = textproto.NewConn(tlsConn)
+	}
+
+	if u.User != nil {
+		password, _ := u.User.Password()
+		code, _, err := ftpCmd(conn, 0, "USER %s", u.User.Username())
+		if err != nil {
+			return errors.Wrap(err, "ftp USER failed")
+		}
+		if code == 331 {
+			code, _, err = ftpCmd(conn, 0, "PASS %s", password)
+			if err != nil {
+				return errors.Wrap(err, "ftp PASS failed")
+			}
+		}
+		if code == 530 {
+			return newAuthError(errors.New("ftp login rejected"))
+		}
+		if code != 230 {
+			return errors.Errorf("ftp login failed with status code %d", code)
+		}
+	}
+
+	if *ftpPassive {
+		if u.Scheme == "ftps" {
+			if _, _, err := ftpCmd(conn, 200, "PBSZ 0"); err != nil {
+				return errors.Wrap(err, "ftp PBSZ failed")
+			}
+			if _, _, err := ftpCmd(conn, 200, "PROT P"); err != nil {
+				return errors.Wrap(err, "ftp PROT failed")
+			}
+		}
+		if _, _, err := ftpCmd(conn, 227, "PASV"); err != nil {
+			return errors.Wrap(err, "ftp server refused passive mode")
+		}
+	}
+
+	_, _, _ = ftpCmd(conn, 221, "QUIT")
+
+	return nil
+}
+
+type FtpChecker struct {
+	Resource string
+}
+
+var _ ResourceChecker = (*FtpChecker)(nil)
+
+func (f *FtpChecker) Check(ctx context.Context) error {
+	return checkFtpResource(ctx, f.Resource)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	mysqlGalera = flag.Bool("mysql-galera", false, "Also require mysql:// resources to be Galera cluster nodes reporting wsrep_ready as ON")
+)
+
+// checkGaleraReady requires the node to report wsrep_ready as ON, which a
+// node only does once it joined the cluster and finished syncing. The local
+// state logged with --verbose shows the progress of the sync.
+func checkGaleraReady(ctx context.Context, db *sql.DB) error {
+	if !*mysqlGalera {
+		return nil
+	}
+	rows, err := db.QueryContext(ctx, "SHOW STATUS LIKE 'wsrep_%'")
+	if err != nil {
+		return errors.Wrap(err, "failed to query wsrep status")
+	}
+	defer rows.Close()
+	status := make(map[string]string)
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return errors.Wrap(err, "failed to read wsrep status")
+		}
+		status[name] = value
+	}
+	if err := rows.Err(); err != nil {
+		return errors.Wrap(err, "failed to read wsrep status")
+	}
+
+	ready, ok := status["wsrep_ready"]
+	if !ok {
+		return newConfigError(errors.New("--mysql-galera requires a Galera node, but the server reports no wsrep_ready status"))
+	}
+	logVerbose("galera node is %s (wsrep_ready %s, cluster size %s, cluster status %s)",
+		status["wsrep_local_state_comment"], ready, status["wsrep_cluster_size"], status["wsrep_cluster_status"])
+	if ready != "ON" {
+		return errors.Errorf("galera node is not ready: wsrep_ready is %s, local state %s", ready, status["wsrep_local_state_comment"])
+	}
+	return nil
+}
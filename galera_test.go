@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+	"testing"
+)
+
+// statusDriver is a database/sql driver answering every query with the rows
+// of status as (Variable_name, Value), like SHOW STATUS.
+type statusDriver struct {
+	mu     sync.Mutex
+	status map[string][][2]string
+}
+
+var testStatus = &statusDriver{status: make(map[string][][2]string)}
+
+func init() {
+	sql.Register("awfi-status", testStatus)
+}
+
+func (d *statusDriver) Open(name string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return statusConn(d.status[name]), nil
+}
+
+type statusConn [][2]string
+
+func (c statusConn) Prepare(string) (driver.Stmt, error) { return statusStmt(c), nil }
+func (c statusConn) Close() error                        { return nil }
+func (c statusConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+type statusStmt [][2]string
+
+func (s statusStmt) Close() error                               { return nil }
+func (s statusStmt) NumInput() int                              { return -1 }
+func (s statusStmt) Exec([]driver.Value) (driver.Result, error) { return nil, driver.ErrSkip }
+func (s statusStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &statusRows{rows: s}, nil
+}
+
+type statusRows struct {
+	rows [][2]string
+	next int
+}
+
+func (r *statusRows) Columns() []string { return []string{"Variable_name", "Value"} }
+func (r *statusRows) Close() error      { return nil }
+func (r *statusRows) Next(dest []driver.Value) error {
+	if r.next == len(r.rows) {
+		return io.EOF
+	}
+	dest[0], dest[1] = r.rows[r.next][0], r.rows[r.next][1]
+	r.next++
+	return nil
+}
+
+func openStatusDB(t *testing.T, status [][2]string) *sql.DB {
+	t.Helper()
+	testStatus.mu.Lock()
+	testStatus.status[t.Name()] = status
+	testStatus.mu.Unlock()
+	db, err := sql.Open("awfi-status", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestCheckGaleraReady(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     [][2]string
+		wantErr    bool
+		wantConfig bool
+	}{
+		{"synced", [][2]string{{"wsrep_local_state_comment", "Synced"}, {"wsrep_ready", "ON"}, {"wsrep_cluster_size", "3"}}, false, false},
+		{"joining", [][2]string{{"wsrep_local_state_comment", "Joining"}, {"wsrep_ready", "OFF"}}, true, false},
+		{"not galera", nil, true, true},
+	}
+	setFlag(t, mysqlGalera, true)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkGaleraReady(context.Background(), openStatusDB(t, tt.status))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkGaleraReady() = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && isConfigError(err) != tt.wantConfig {
+				t.Errorf("isConfigError(%v) = %v, want %v", err, isConfigError(err), tt.wantConfig)
+			}
+		})
+	}
+}
+
+func TestCheckGaleraReadyDisabled(t *testing.T) {
+	setFlag(t, mysqlGalera, false)
+	if err := checkGaleraReady(context.Background(), openStatusDB(t, nil)); err != nil {
+		t.Errorf("checkGaleraReady() = %v, want nil without --mysql-galera", err)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+var (
+	githubAnnotations = flag.Bool("github-annotations", false, "Emit GitHub Actions error annotations for unready resources (enabled automatically when GITHUB_ACTIONS=true)")
+)
+
+func githubAnnotationsEnabled() bool {
+	return *githubAnnotations || os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+// escapeGithubData escapes a workflow command message, see
+// https://github.com/actions/toolkit/blob/main/packages/core/src/command.ts
+func escapeGithubData(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	s = strings.ReplaceAll(s, "\n", "%0A")
+	return s
+}
+
+func escapeGithubProperty(s string) string {
+	s = escapeGithubData(s)
+	s = strings.ReplaceAll(s, ":", "%3A")
+	s = strings.ReplaceAll(s, ",", "%2C")
+	return s
+}
+
+func writeGithubError(w io.Writer, resource string, err error) {
+	_, _ = fmt.Fprintf(w, "::error title=%s::%s\n",
+		escapeGithubProperty("awfi: "+resource+" is not ready"),
+		escapeGithubData(err.Error()),
+	)
+}
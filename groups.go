@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	groupsFile = flag.String("groups-file", "", "YAML file combining resources with nested and/or groups; awfi succeeds once the root group is satisfied")
+)
+
+// groupNode is either a leaf referring to a resource or an "and"/"or" group.
+type groupNode struct {
+	Op       string
+	Leaf     int
+	Children []*groupNode
+}
+
+// groupState is the three-valued outcome of a group node.
+type groupState int
+
+const (
+	groupPending groupState = iota
+	groupSatisfied
+	groupFailed
+)
+
+func (s groupState) String() string {
+	switch s {
+	case groupSatisfied:
+		return "satisfied"
+	case groupFailed:
+		return "not satisfied"
+	default:
+		return "undecided"
+	}
+}
+
+// readGroupsFile parses a tree of the form
+//
+//	and:
+//	  - db=postgres://localhost:5432/app
+//	  - or:
+//	      - http://cache-a:8080/health
+//	      - http://cache-b:8080/health
+//
+// into its root node and the leaf resources it refers to. Like
+// readResourcesFile, every invalid leaf is reported, each error pointing at
+// its line, while a malformed group stops parsing.
+func readGroupsFile(path string) (*groupNode, []resourceSpec, []error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, []error{errors.Wrap(err, "failed to read groups file")}
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, nil, []error{errors.Wrapf(err, "failed to parse groups file %s", path)}
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil, []error{errors.Errorf("%s: empty groups file", path)}
+	}
+
+	var specs []resourceSpec
+	var errs []error
+	root, err := parseGroupNode(path, doc.Content[0], &specs, &errs)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return nil, nil, errs
+	}
+	return root, specs, nil
+}
+
+// parseGroupNode appends invalid leaves to errs and carries on, returning an
+// error only for a malformed group.
+func parseGroupNode(path string, node *yaml.Node, specs *[]resourceSpec, errs *[]error) (*groupNode, error) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		spec := parseResourceArg(strings.TrimSpace(node.Value))
+		if spec.Resource == "" {
+			*errs = append(*errs, errors.Errorf("%s:%d: missing resource for %s", path, node.Line, spec.Name))
+			return nil, nil
+		}
+		expanded, err := expandSpec(spec)
+		if err != nil {
+			*errs = append(*errs, errors.Wrapf(err, "%s:%d", path, node.Line))
+			return nil, nil
+		}
+		// A leaf expanding to several resources needs all of them, like
+		// the same resource given as an argument.
+		group := &groupNode{Op: "and"}
+		for _, spec := range expanded {
+			if checkerForResource(spec.Resource) == nil {
+				*errs = append(*errs, errors.Errorf("%s:%d: unsupported resource type: %s", path, node.Line, redactResource(spec.Resource)))
+				continue
+			}
+			*specs = append(*specs, spec)
+			group.Children = append(group.Children, &groupNode{Leaf: len(*specs) - 1})
+		}
+		if len(group.Children) == 1 {
+			return group.Children[0], nil
+		}
+		return group, nil
+	case yaml.MappingNode:
+		if len(node.Content) != 2 {
+			return nil, errors.Errorf("%s:%d: a group must have exactly one of and or or", path, node.Line)
+		}
+		op, members := node.Content[0].Value, node.Content[1]
+		if op != "and" && op != "or" {
+			return nil, errors.Errorf("%s:%d: unknown group operator %q, expected and or or", path, node.Line, op)
+		}
+		if members.Kind != yaml.SequenceNode || len(members.Content) == 0 {
+			return nil, errors.Errorf("%s:%d: %s group needs a list of members", path, members.Line, op)
+		}
+		group := &groupNode{Op: op}
+		for _, member := range members.Content {
+			child, err := parseGroupNode(path, member, specs, errs)
+			if err != nil {
+				return nil, err
+			}
+			group.Children = append(group.Children, child)
+		}
+		return group, nil
+	default:
+		return nil, errors.Errorf("%s:%d: expected a resource or a group", path, node.Line)
+	}
+}
+
+// eval decides the node from the results of the finished leaves.
+func (n *groupNode) eval(results []resourceResult, finished []bool) groupState {
+	if n.Op == "" {
+		switch {
+		case !finished[n.Leaf]:
+			return groupPending
+		case results[n.Leaf].Ready:
+			return groupSatisfied
+		default:
+			return groupFailed
+		}
+	}
+
+	// decisive is the child state that decides the group on its own.
+	decisive, other := groupFailed, groupSatisfied
+	if n.Op == "or" {
+		decisive, other = groupSatisfied, groupFailed
+	}
+	allOther := true
+	for _, child := range n.Children {
+		state := child.eval(results, finished)
+		if state == decisive {
+			return decisive
+		}
+		allOther = allOther && state == other
+	}
+	if allOther {
+		return other
+	}
+	return groupPending
+}
+
+// shortCircuited reports whether the node was decided while some of its
+// members were still undecided.
+func (n *groupNode) shortCircuited(results []resourceResult, finished []bool) bool {
+	if n.Op == "" || n.eval(results, finished) == groupPending {
+		return false
+	}
+	for _, child := range n.Children {
+		if child.eval(results, finished) == groupPending {
+			return true
+		}
+	}
+	return false
+}
+
+// waitForGroup waits for every leaf concurrently and stops the remaining
+// waits as soon as the root is decided. Leaves stopped that way are marked
+// as skipped.
+func waitForGroup(ctx context.Context, root *groupNode, n int, wait func(context.Context, int) resourceResult) []resourceResult {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type leafResult struct {
+		i      int
+		result resourceResult
+	}
+	done := make(chan leafResult)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			done <- leafResult{i: i, result: wait(ctx, i)}
+		}(i)
+	}
+
+	results := make([]resourceResult, n)
+	finished := make([]bool, n)
+	decided := false
+	for remaining := n; remaining > 0; remaining-- {
+		leaf := <-done
+		results[leaf.i] = leaf.result
+		if decided {
+			results[leaf.i].Skipped = true
+			results[leaf.i].Error = "skipped, group already decided"
+			continue
+		}
+		finished[leaf.i] = true
+		if root.eval(results, finished) != groupPending {
+			decided = true
+			cancel()
+		}
+	}
+	return results
+}
+
+// finishedLeaves treats every leaf that was not skipped as finished.
+func finishedLeaves(results []resourceResult) []bool {
+	finished := make([]bool, len(results))
+	for i, result := range results {
+		finished[i] = !result.Skipped
+	}
+	return finished
+}
+
+// writeGroupTree prints every group with its state, and every leaf with its
+// readiness, noting the groups decided before all their members finished.
+func writeGroupTree(w io.Writer, n *groupNode, results []resourceResult, depth int) {
+	indent := strings.Repeat("  ", depth)
+	if n.Op == "" {
+		result := results[n.Leaf]
+		state := colorize(w, colorGreen, "ready")
+		switch {
+		case result.Skipped:
+			state = "skipped"
+		case !result.Ready:
+			state = colorize(w, colorRed, "not ready")
+		}
+		_, _ = fmt.Fprintf(w, "%s%s: %s\n", indent, result.Name, state)
+		return
+	}
+
+	finished := finishedLeaves(results)
+	line := fmt.Sprintf("%s%s: %s", indent, n.Op, n.eval(results, finished))
+	if n.shortCircuited(results, finished) {
+		line += " (short-circuited)"
+	}
+	_, _ = fmt.Fprintln(w, line)
+	for _, child := range n.Children {
+		writeGroupTree(w, child, results, depth+1)
+	}
+}
+
+// reportGroup prints the group tree in text output and reports whether the
+// root group is satisfied.
+func reportGroup(w io.Writer, root *groupNode, results []resourceResult) bool {
+	finished := finishedLeaves(results)
+	satisfied := root.eval(results, finished) == groupSatisfied
+
+	if *outputFormat == "text" && !(satisfied && *quiet) {
+		writeGroupTree(w, root, results, 0)
+	}
+	return satisfied
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadGroupsFileExpandsLeaves(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "groups.yaml")
+	content := `and:
+  - db=postgres://localhost:5432/app
+  - or:
+      - cache=redis://cache-[1-2]:6379
+      - http://web-{blue,green}:8080/health
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	root, specs, errs := readGroupsFile(path)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	wantNames := []string{"db", "cache-1", "cache-2", "http://web-blue:8080/health", "http://web-green:8080/health"}
+	if len(specs) != len(wantNames) {
+		t.Fatalf("got %d resources, want %d", len(specs), len(wantNames))
+	}
+	for i, spec := range specs {
+		if spec.Name != wantNames[i] {
+			t.Errorf("resource %d = %s, want %s", i, spec.Name, wantNames[i])
+		}
+	}
+
+	or := root.Children[1]
+	if or.Op != "or" || len(or.Children) != 2 {
+		t.Fatalf("or group = %+v, want two members", or)
+	}
+	for _, member := range or.Children {
+		if member.Op != "and" || len(member.Children) != 2 {
+			t.Errorf("expanded leaf = %+v, want an and group of two", member)
+		}
+	}
+	if root.Children[0].Op != "" {
+		t.Errorf("leaf without a range became a %s group", root.Children[0].Op)
+	}
+
+	// One cache replica being ready is not enough for its leaf.
+	results := make([]resourceResult, len(specs))
+	finished := make([]bool, len(specs))
+	for _, i := range []int{0, 1} {
+		results[i].Ready, finished[i] = true, true
+	}
+	finished[2] = true
+	if state := root.eval(results, finished); state != groupPending {
+		t.Errorf("root = %s with one cache replica failed, want undecided", state)
+	}
+	for _, i := range []int{3, 4} {
+		results[i].Ready, finished[i] = true, true
+	}
+	if state := root.eval(results, finished); state != groupSatisfied {
+		t.Errorf("root = %s with both web colors ready, want satisfied", state)
+	}
+}
+
+func TestReadGroupsFileReportsEveryInvalidLeaf(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "groups.yaml")
+	content := `and:
+  - db=postgres://localhost:5432/app
+  - foo://x
+  - or:
+      - cache=
+      - http://web-[3-1]:8080/health
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	root, specs, errs := readGroupsFile(path)
+	if root != nil || specs != nil {
+		t.Errorf("readGroupsFile() returned a group along with errors")
+	}
+	want := []string{
+		path + ":3: unsupported resource type: foo://x",
+		path + ":5: missing resource for cache",
+	}
+	if len(errs) != len(want)+1 {
+		t.Fatalf("readGroupsFile() = %v, want %d errors", errs, len(want)+1)
+	}
+	for i := range want {
+		if errs[i].Error() != want[i] {
+			t.Errorf("error %d = %q, want %q", i, errs[i], want[i])
+		}
+	}
+	if !strings.HasPrefix(errs[2].Error(), path+":6: ") {
+		t.Errorf("error 2 = %q, want it located on line 6", errs[2])
+	}
+}
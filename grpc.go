@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+)
+
+func isGrpcResource(resource string) bool {
+	return strings.HasPrefix(resource, "grpc://") || strings.HasPrefix(resource, "grpcs://")
+}
+
+// grpcReflectionServices are not counted as advertised services, since every
+// server with reflection enabled lists them.
+var grpcReflectionServices = map[string]bool{
+	"grpc.reflection.v1.ServerReflection":      true,
+	"grpc.reflection.v1alpha.ServerReflection": true,
+}
+
+// checkGrpcResource lists the services of a gRPC server through its
+// reflection API, for servers that do not implement the health protocol. It
+// succeeds once at least one service besides reflection itself is advertised.
+// Servers that only offer the older v1alpha reflection API are asked through
+// it instead.
+func checkGrpcResource(ctx context.Context, resource string) error {
+	cappedCtx, cancel := context.WithTimeout(ctx, checkTimeout(ctx))
+	defer cancel()
+
+	u, err := url.Parse(resource)
+	if err != nil {
+		return newConfigError(errors.Wrap(err, "failed to parse grpc url"))
+	}
+	if u.Hostname() == "" {
+		return newConfigError(errors.New("grpc resources need a host"))
+	}
+	secure := u.Scheme == "grpcs"
+	address := u.Host
+	if u.Port() == "" {
+		port := "80"
+		if secure {
+			port = "443"
+		}
+		address = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	// The connection, including the TLS handshake, is set up here rather than
+	// by grpc, which only reports failures as text. Keeping the error lets a
+	// refused connection, an unknown host, or an untrusted certificate be
+	// told apart from a server without reflection.
+	var mu sync.Mutex
+	var dialErr error
+	dial := func(ctx context.Context, address string) (net.Conn, error) {
+		conn, err := dialContext(ctx, "tcp", address)
+		if err == nil {
+			logConnection("%s: connected to %s from %s", address, conn.RemoteAddr(), conn.LocalAddr())
+			if secure {
+				tlsConn := tls.Client(conn, &tls.Config{ServerName: u.Hostname(), NextProtos: []string{"h2"}})
+				if err = tlsConn.HandshakeContext(ctx); err != nil {
+					_ = conn.Close()
+				}
+				conn = tlsConn
+			}
+		}
+		if err != nil {
+			mu.Lock()
+			dialErr = err
+			mu.Unlock()
+			return nil, err
+		}
+		return conn, nil
+	}
+
+	conn, err := grpc.NewClient("passthrough:///"+address,
+		grpc.WithContextDialer(dial),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		return newConfigError(errors.Wrap(err, "failed to create grpc client"))
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	services, err := listGrpcServices(cappedCtx, conn)
+	if status.Code(err) == codes.Unimplemented {
+		services, err = listGrpcServicesV1Alpha(cappedCtx, conn)
+		if status.Code(err) == codes.Unimplemented {
+			return newConfigError(errors.New("grpc server reflection is not enabled"))
+		}
+	}
+	if err != nil {
+		mu.Lock()
+		defer mu.Unlock()
+		if dialErr != nil {
+			return errors.Wrap(dialErr, "failed to connect")
+		}
+		return errors.Wrap(err, "failed to list grpc services")
+	}
+
+	var advertised []string
+	for _, service := range services {
+		if !grpcReflectionServices[service] {
+			advertised = append(advertised, service)
+		}
+	}
+	logVerbose("grpc server advertises %d services: %s", len(advertised), strings.Join(advertised, ", "))
+	if len(advertised) == 0 {
+		return errors.New("grpc server advertises no services yet")
+	}
+	return nil
+}
+
+// listGrpcServices asks for the service list through the v1 reflection API.
+func listGrpcServices(ctx context.Context, conn *grpc.ClientConn) ([]string, error) {
+	stream, err := reflectionv1.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// A failed send only says the stream ended; Recv returns the reason.
+	if err := stream.Send(&reflectionv1.ServerReflectionRequest{
+		MessageRequest: &reflectionv1.ServerReflectionRequest_ListServices{},
+	}); err != nil && err != io.EOF {
+		return nil, err
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	_ = stream.CloseSend()
+	if e := resp.GetErrorResponse(); e != nil {
+		return nil, status.Error(codes.Code(e.GetErrorCode()), e.GetErrorMessage())
+	}
+	var services []string
+	for _, service := range resp.GetListServicesResponse().GetService() {
+		services = append(services, service.GetName())
+	}
+	return services, nil
+}
+
+// listGrpcServicesV1Alpha is listGrpcServices for the v1alpha reflection API.
+func listGrpcServicesV1Alpha(ctx context.Context, conn *grpc.ClientConn) ([]string, error) {
+	stream, err := reflectionv1alpha.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.Send(&reflectionv1alpha.ServerReflectionRequest{
+		MessageRequest: &reflectionv1alpha.ServerReflectionRequest_ListServices{},
+	}); err != nil && err != io.EOF {
+		return nil, err
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	_ = stream.CloseSend()
+	if e := resp.GetErrorResponse(); e != nil {
+		return nil, status.Error(codes.Code(e.GetErrorCode()), e.GetErrorMessage())
+	}
+	var services []string
+	for _, service := range resp.GetListServicesResponse().GetService() {
+		services = append(services, service.GetName())
+	}
+	return services, nil
+}
+
+type GrpcReflectChecker struct {
+	Resource string
+}
+
+var _ ResourceChecker = (*GrpcReflectChecker)(nil)
+
+func (g *GrpcReflectChecker) Check(ctx context.Context) error {
+	return checkGrpcResource(ctx, g.Resource)
+}
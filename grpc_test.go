@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// startGrpcServer serves srv on a local port, returning its grpc:// resource.
+func startGrpcServer(t *testing.T, srv *grpc.Server) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = srv.Serve(ln)
+	}()
+	t.Cleanup(srv.Stop)
+	return "grpc://" + ln.Addr().String()
+}
+
+func TestGrpcReflectChecker(t *testing.T) {
+	t.Run("advertised service", func(t *testing.T) {
+		srv := grpc.NewServer()
+		healthpb.RegisterHealthServer(srv, health.NewServer())
+		reflection.Register(srv)
+		checker := &GrpcReflectChecker{Resource: startGrpcServer(t, srv)}
+		if err := checker.Check(context.Background()); err != nil {
+			t.Errorf("Check() = %v, want nil", err)
+		}
+	})
+
+	t.Run("only reflection", func(t *testing.T) {
+		srv := grpc.NewServer()
+		reflection.Register(srv)
+		checker := &GrpcReflectChecker{Resource: startGrpcServer(t, srv)}
+		err := checker.Check(context.Background())
+		if err == nil || isConfigError(err) {
+			t.Errorf("Check() = %v, want a retryable error", err)
+		}
+	})
+
+	t.Run("reflection not enabled", func(t *testing.T) {
+		srv := grpc.NewServer()
+		healthpb.RegisterHealthServer(srv, health.NewServer())
+		checker := &GrpcReflectChecker{Resource: startGrpcServer(t, srv)}
+		if err := checker.Check(context.Background()); !errors.Is(err, ErrConfig) {
+			t.Errorf("Check() = %v, want a configuration error", err)
+		}
+	})
+
+	t.Run("connection refused", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr := ln.Addr().String()
+		_ = ln.Close()
+		checker := &GrpcReflectChecker{Resource: "grpc://" + addr}
+		err = checker.Check(context.Background())
+		var opErr *net.OpError
+		if !errors.As(err, &opErr) || isConfigError(err) {
+			t.Errorf("Check() = %v, want a retryable dial error", err)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+var (
+	heartbeat = flag.Duration("heartbeat", 0, "Print a line to stderr for every resource still being waited for at this interval, e.g. to keep CI jobs from being killed as hung (0 to disable)")
+)
+
+// heartbeats tracks the resources still being waited for, for --heartbeat.
+type heartbeats struct {
+	mu       sync.Mutex
+	w        io.Writer
+	start    time.Time
+	names    []string
+	attempts []int
+	done     []bool
+}
+
+func newHeartbeats(w io.Writer, specs []resourceSpec) *heartbeats {
+	h := &heartbeats{
+		w:        w,
+		start:    time.Now(),
+		names:    make([]string, len(specs)),
+		attempts: make([]int, len(specs)),
+		done:     make([]bool, len(specs)),
+	}
+	for i, spec := range specs {
+		h.names[i] = spec.Name
+	}
+	return h
+}
+
+func (h *heartbeats) update(i int, result CheckResult) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.attempts[i] = result.Attempt
+	h.done[i] = result.Done
+}
+
+// run prints a heartbeat every interval until stop is closed.
+func (h *heartbeats) run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			h.beat()
+		}
+	}
+}
+
+func (h *heartbeats) beat() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	elapsed := time.Since(h.start).Round(time.Second)
+	for i, name := range h.names {
+		if !h.done[i] {
+			_, _ = fmt.Fprintf(h.w, "still waiting on %s (attempt %d, %s elapsed)\n", name, h.attempts[i], elapsed)
+		}
+	}
+}
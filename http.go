@@ -0,0 +1,599 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	httpMaxLatency        = flag.Duration("http-max-latency", 0, "Treat HTTP responses slower than this as not ready, e.g. 500ms (0 to disable)")
+	httpExpectContentType = flag.String("http-expect-content-type", "", "Require the HTTP response Content-Type to start with this media type, ignoring parameters such as charset")
+	httpMethod            = flag.String("http-method", "GET", "HTTP method used by HTTP checks")
+	httpBody              = flag.String("http-body", "", "Request body sent by HTTP checks")
+	httpBodyFile          = flag.String("http-body-file", "", "File whose contents are sent as the request body by HTTP checks")
+	httpContentType       = flag.String("http-content-type", "", "Content-Type header sent with the HTTP request body")
+	httpCookieJar         = flag.Bool("http-cookie-jar", false, "Keep cookies set by responses, including redirects, for the rest of an HTTP check attempt")
+	httpVersion           = flag.String("http-version", "auto", "HTTP version used by HTTP checks: 1.1, 2, or auto")
+	httpExpectFinalURL    = flag.String("http-expect-final-url", "", "Require the URL reached after following redirects to start with this prefix")
+	httpExpectIssuer      = flag.String("http-expect-issuer", "", "Require the HTTPS leaf certificate's issuer common name or organization to equal this value")
+	httpExpectStatus      = flag.String("http-expect-status", "200", "Comma-separated HTTP status codes that count as available")
+	httpRetryStatus       = flag.String("http-retry-status", "", "Comma-separated HTTP status codes to retry; any other unexpected status stops the wait (by default every status is retried)")
+	httpReadBody          = flag.Bool("http-read-body", false, "Read the HTTP response body, up to --http-max-body bytes, before reporting success")
+	httpMaxBody           = flag.Int64("http-max-body", 1<<20, "Maximum number of HTTP response body bytes read")
+	httpStable            = flag.Bool("http-stable", false, "Require the HTTP response body, or the value at --http-json-path when set, to be identical across the --repeated-successes consecutive successes; a change counts as a failed attempt")
+	httpDisableKeepalive  = flag.Bool("http-disable-keepalive", false, "Open a new connection for every HTTP check attempt instead of reusing the previous one")
+	httpIdleTimeout       = flag.Duration("http-idle-timeout", 0, "Close HTTP connections kept between attempts after being idle this long (0 for Go's default of 90s)")
+	minHealthyEndpoints   = flag.Int("min-healthy-endpoints", 0, "Resolve the host of HTTP resources and require this many of its addresses to pass the check individually (0 to check the host as usual)")
+	httpAllAddresses      = flag.Bool("http-all-addresses", false, "Resolve the host of HTTP resources and require every one of its addresses to pass the check individually")
+	httpUserAgent         = flag.String("http-user-agent", "awfi/"+awfiVersion(), "User-Agent header sent by HTTP checks (empty to send none)")
+
+	httpExpectHeaders repeatedFlag
+	httpCookies       repeatedFlag
+)
+
+func init() {
+	flag.Var(&httpExpectHeaders, "http-expect-header", "Require an HTTP response header, as \"Name: Value\" or just \"Name\" to only require its presence (repeatable)")
+	flag.Var(&httpCookies, "http-cookie", "Cookie sent with HTTP checks, as \"name=value\" (repeatable)")
+}
+
+func isHttpResource(resource string) bool {
+	return strings.HasPrefix(resource, "http://") || strings.HasPrefix(resource, "https://")
+}
+
+// newHttpTransport returns a transport configured like every HTTP-based
+// checker's. Checkers create one and keep it across attempts, so connections
+// are reused and closed once the wait ends.
+func newHttpTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// The proxy flag is validated at startup.
+	transport.Proxy, _ = proxyFunc()
+	if *socks5Proxy != "" || *resolverAddr != "" || *localAddr != "" || *minHealthyEndpoints > 0 || *httpAllAddresses {
+		transport.DialContext = dialContext
+	}
+	return transport
+}
+
+// newHttpClient returns a client using transport. It has no timeout of its
+// own, since every request is bounded by the context of its check.
+func newHttpClient(transport *http.Transport) *http.Client {
+	return &http.Client{
+		Transport: transport,
+	}
+}
+
+// newHttpCheckTransport returns a transport configured by the HTTP check
+// flags.
+func newHttpCheckTransport() *http.Transport {
+	transport := newHttpTransport()
+	setHttpVersion(transport, *httpVersion)
+	transport.DisableKeepAlives = *httpDisableKeepalive
+	if *httpIdleTimeout > 0 {
+		transport.IdleConnTimeout = *httpIdleTimeout
+	}
+	return transport
+}
+
+// checkHttpResource checks the resource against the HTTP flags. A non-empty
+// expectStatus overrides --http-expect-status, and headers are added to the
+// request. Connections are reused through transport; when it is nil, a
+// transport is created for this attempt only. A non-nil checkResponse receives
+// the response and its body once every other check passed.
+func checkHttpResource(ctx context.Context, transport *http.Transport, resource string, expectStatus string, headers map[string]string, checkResponse func(*http.Response, []byte) error) error {
+	cappedCtx, cancel := context.WithTimeout(ctx, checkTimeout(ctx))
+	defer cancel()
+
+	if transport == nil {
+		transport = newHttpCheckTransport()
+		defer transport.CloseIdleConnections()
+		if isPinned(ctx) {
+			// A pinned check must reach the address itself, not a proxy.
+			transport.Proxy = nil
+		}
+	}
+	cx := newHttpClient(transport)
+	if *httpCookieJar {
+		// Like the client, the jar only lives for this attempt.
+		cx.Jar, _ = cookiejar.New(nil)
+	}
+
+	body, err := httpRequestBody()
+	if err != nil {
+		return newConfigError(err)
+	}
+
+	// A fresh reader is needed for every attempt, since sending a request
+	// consumes its body.
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(cappedCtx, *httpMethod, resource, bodyReader)
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+	// An empty User-Agent keeps net/http from adding its own.
+	req.Header.Set("User-Agent", *httpUserAgent)
+	if *httpContentType != "" {
+		req.Header.Set("Content-Type", *httpContentType)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	if *httpBearerCommand != "" {
+		token, err := bearerTokens.get(cappedCtx)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for _, cookie := range httpCookies {
+		name, value, _ := strings.Cut(cookie, "=")
+		req.AddCookie(&http.Cookie{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value)})
+	}
+	setTraceHeader(ctx, req)
+	req = withConnectionTrace(req, resource)
+
+	start := time.Now()
+	resp, err := cx.Do(req)
+	if err != nil {
+		if isTLSHandshakeError(err) {
+			return errors.Wrap(err, "tls handshake failed")
+		}
+		return errors.Wrap(err, "failed to perform request")
+	}
+	latency := time.Since(start)
+	logVerbose("%s responded with status %d over %s in %s", redactResource(resource), resp.StatusCode, resp.Proto, latency)
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if *httpBearerCommand != "" && resp.StatusCode == http.StatusUnauthorized {
+		// The token may have expired before its TTL, so the next attempt
+		// fetches a fresh one.
+		bearerTokens.invalidate()
+	}
+
+	if *httpVersion == "2" && resp.ProtoMajor != 2 {
+		return newConfigError(errors.Errorf("server responded over %s, want HTTP/2", resp.Proto))
+	}
+
+	if err := checkIssuer(resp, *httpExpectIssuer); err != nil {
+		return err
+	}
+
+	if expectStatus == "" {
+		expectStatus = *httpExpectStatus
+	}
+	if err := checkStatusCode(resp.StatusCode, expectStatus); err != nil {
+		return err
+	}
+
+	// resp.Request is the last request sent, after any redirects.
+	if finalURL := resp.Request.URL.String(); !strings.HasPrefix(finalURL, *httpExpectFinalURL) {
+		return errors.Errorf("redirected to %s, want a URL starting with %s", redactResource(finalURL), *httpExpectFinalURL)
+	}
+
+	if *httpMaxLatency > 0 && latency > *httpMaxLatency {
+		return errors.Errorf("response took %s, more than the allowed %s", latency, *httpMaxLatency)
+	}
+
+	if err := checkExpectedHeaders(resp.Header, httpExpectHeaders); err != nil {
+		return err
+	}
+
+	if err := checkContentType(resp.Header.Get("Content-Type"), *httpExpectContentType); err != nil {
+		return err
+	}
+
+	// Without anything asserting on the body, only the status matters, so a
+	// connection dropped while reading it is not a failure.
+	assertsBody := checkResponse != nil || *successScript != "" || *httpJSONPath != ""
+	if httpBodyRequired() || assertsBody {
+		// Every assertion below sees the same decompressed body.
+		body, err := readHttpBody(resp)
+		if err != nil && !assertsBody {
+			logVerbose("%s: ignoring %v after status %d", redactResource(resource), err, resp.StatusCode)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if *httpJSONPath != "" {
+			if err := checkJSONPath(resource, body); err != nil {
+				return err
+			}
+		}
+		if *successScript != "" {
+			if err := runSuccessScript(cappedCtx, resp, body); err != nil {
+				return err
+			}
+		}
+		if checkResponse != nil {
+			return checkResponse(resp, body)
+		}
+	}
+
+	return nil
+}
+
+// checkHttpEndpoints resolves the resource's host and checks every address
+// concurrently, requiring at least minHealthy of them to pass, or all of them
+// when minHealthy is zero.
+func checkHttpEndpoints(ctx context.Context, resource string, expectStatus string, headers map[string]string, minHealthy int) error {
+	u, err := url.Parse(resource)
+	if err != nil {
+		return newConfigError(errors.Wrap(err, "failed to parse url"))
+	}
+	host := u.Hostname()
+
+	lookupCtx, cancel := context.WithTimeout(ctx, checkTimeout(ctx))
+	defer cancel()
+	addrs, err := resolver().LookupHost(lookupCtx, host)
+	if err != nil {
+		return errors.Wrapf(err, "failed to resolve %s", host)
+	}
+
+	errs := make([]error, len(addrs))
+	var wg sync.WaitGroup
+	for i, addr := range addrs {
+		wg.Add(1)
+		go func(i int, addr string) {
+			defer wg.Done()
+			// Pooled connections would not honor the pinned address, so
+			// every endpoint gets a transport of its own.
+			errs[i] = checkHttpResource(withPinnedHost(ctx, host, addr), nil, resource, expectStatus, headers, nil)
+		}(i, addr)
+	}
+	wg.Wait()
+
+	healthy := 0
+	var lastErr error
+	var lastAddr string
+	for i, addr := range addrs {
+		if errs[i] == nil {
+			healthy++
+			logVerbose("%s: endpoint %s is healthy", redactResource(resource), addr)
+			continue
+		}
+		logVerbose("%s: endpoint %s is not healthy: %v", redactResource(resource), addr, errs[i])
+		lastErr, lastAddr = errs[i], addr
+	}
+	if minHealthy == 0 {
+		minHealthy = len(addrs)
+	}
+	if healthy >= minHealthy {
+		return nil
+	}
+	if lastErr == nil {
+		return errors.Errorf("%s has %d endpoints, want %d healthy", host, len(addrs), minHealthy)
+	}
+	// Wrapping keeps the failure's classification, e.g. as a config error.
+	return errors.Wrapf(lastErr, "%d of %d endpoints of %s are healthy, want %d; %s", healthy, len(addrs), host, minHealthy, lastAddr)
+}
+
+// httpBodyRequired reports whether any configured option needs the response
+// body. Otherwise only the status line and headers are waited for.
+func httpBodyRequired() bool {
+	return *httpReadBody
+}
+
+// readHttpBody reads at most --http-max-body bytes of the decompressed
+// response body, so a huge or endless body cannot stall the check or exhaust
+// memory. A body cut short is reported as truncated, and a corrupt compressed
+// one as such; both are retried like any other failure.
+func readHttpBody(resp *http.Response) ([]byte, error) {
+	decoded, err := decodedBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(io.LimitReader(decoded, *httpMaxBody))
+	switch {
+	case err == nil:
+		return body, nil
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return nil, errors.Wrapf(err, "response body truncated after %d bytes", len(body))
+	case decoded != io.Reader(resp.Body):
+		return nil, errors.Wrap(err, "failed to decompress response body")
+	default:
+		return nil, errors.Wrap(err, "failed to read response body")
+	}
+}
+
+// parseStatusList parses a comma-separated list of HTTP status codes.
+func parseStatusList(list string) (map[int]bool, error) {
+	statuses := make(map[int]bool)
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		code, err := strconv.Atoi(field)
+		if err != nil || code < 100 || code > 999 {
+			return nil, errors.Errorf("invalid HTTP status code %q", field)
+		}
+		statuses[code] = true
+	}
+	return statuses, nil
+}
+
+func validHttpVersion(version string) bool {
+	switch version {
+	case "1.1", "2", "auto":
+		return true
+	default:
+		return false
+	}
+}
+
+// setHttpVersion restricts the transport to HTTP/1.1 or insists on HTTP/2.
+// HTTP/2 is negotiated through TLS ALPN, so it requires an https:// resource.
+func setHttpVersion(transport *http.Transport, version string) {
+	switch version {
+	case "1.1":
+		// A non-nil, empty TLSNextProto disables HTTP/2.
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	case "2":
+		transport.ForceAttemptHTTP2 = true
+	}
+}
+
+// httpRequestBody returns the body given by --http-body or --http-body-file,
+// or nil when neither is set.
+func httpRequestBody() ([]byte, error) {
+	if *httpBodyFile != "" {
+		body, err := os.ReadFile(*httpBodyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read --http-body-file")
+		}
+		return body, nil
+	}
+	if *httpBody != "" {
+		return []byte(*httpBody), nil
+	}
+	return nil, nil
+}
+
+// validateHttpFlags checks the HTTP request and status flags at startup, so
+// the checker can ignore parse errors.
+func validateHttpFlags() error {
+	if !validHttpVersion(*httpVersion) {
+		return errors.Errorf("invalid --http-version %q, expected 1.1, 2, or auto", *httpVersion)
+	}
+	if *httpMethod == "" || strings.ContainsAny(*httpMethod, " \t\r\n") {
+		return errors.Errorf("invalid --http-method %q", *httpMethod)
+	}
+	if err := validateTransitionFlags(); err != nil {
+		return err
+	}
+	if *httpAllAddresses && *minHealthyEndpoints > 0 {
+		return errors.New("--http-all-addresses and --min-healthy-endpoints cannot be combined")
+	}
+	if *httpBody != "" && *httpBodyFile != "" {
+		return errors.New("--http-body and --http-body-file cannot be combined")
+	}
+	if _, err := httpRequestBody(); err != nil {
+		return err
+	}
+	if err := validateSuccessScript(); err != nil {
+		return err
+	}
+	if err := validateJSONPathFlags(); err != nil {
+		return err
+	}
+	for _, cookie := range httpCookies {
+		if name, _, ok := strings.Cut(cookie, "="); !ok || strings.TrimSpace(name) == "" {
+			return errors.Errorf("invalid --http-cookie %q, expected name=value", cookie)
+		}
+	}
+
+	expected, err := parseStatusList(*httpExpectStatus)
+	if err != nil {
+		return errors.Wrap(err, "invalid --http-expect-status")
+	}
+	if len(expected) == 0 {
+		return errors.New("--http-expect-status requires at least one status code")
+	}
+	if _, err := parseStatusList(*httpRetryStatus); err != nil {
+		return errors.Wrap(err, "invalid --http-retry-status")
+	}
+	return nil
+}
+
+// checkStatusCode accepts the expected codes. Other codes are retried, unless
+// --http-retry-status is set and does not list them, in which case the wait
+// stops.
+func checkStatusCode(code int, expectStatus string) error {
+	expected, _ := parseStatusList(expectStatus)
+	if expected[code] {
+		return nil
+	}
+	err := errors.Errorf("unexpected status code %d", code)
+	retry, _ := parseStatusList(*httpRetryStatus)
+	if len(retry) > 0 && !retry[code] {
+		return newFatalError(err)
+	}
+	return err
+}
+
+// checkIssuer compares the issuer of the leaf certificate against the
+// expected common name or organization. A mismatch is retried, so a wait can
+// last until a rotated certificate is served.
+func checkIssuer(resp *http.Response, expected string) error {
+	if expected == "" {
+		return nil
+	}
+	if resp.TLS == nil || len(resp.TLS.PeerCertificates) == 0 {
+		return newConfigError(errors.New("--http-expect-issuer requires an https:// resource"))
+	}
+	issuer := resp.TLS.PeerCertificates[0].Issuer
+	logVerbose("%s is served with a certificate issued by %s", redactResource(resp.Request.URL.String()), issuer)
+	if issuer.CommonName == expected {
+		return nil
+	}
+	for _, org := range issuer.Organization {
+		if org == expected {
+			return nil
+		}
+	}
+	return errors.Errorf("certificate issued by %s, want %s", issuer, expected)
+}
+
+// checkExpectedHeaders matches "Name: Value" expectations against any value
+// of the named header, and "Name" expectations against its presence.
+func checkExpectedHeaders(header http.Header, expectations []string) error {
+	for _, expectation := range expectations {
+		name, want, hasValue := strings.Cut(expectation, ":")
+		name = strings.TrimSpace(name)
+		values := header.Values(name)
+		if len(values) == 0 {
+			return errors.Errorf("response header %s is missing", name)
+		}
+		if !hasValue {
+			continue
+		}
+		want = strings.TrimSpace(want)
+		matched := false
+		for _, value := range values {
+			if strings.TrimSpace(value) == want {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return errors.Errorf("response header %s is %q, want %q", name, strings.Join(values, ", "), want)
+		}
+	}
+	return nil
+}
+
+// checkContentType prefix-matches the media type of a Content-Type header,
+// so "application/json" accepts "application/json; charset=utf-8".
+func checkContentType(contentType string, expected string) error {
+	if expected == "" {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.TrimSpace(contentType)
+	}
+	if !strings.HasPrefix(strings.ToLower(mediaType), strings.ToLower(strings.TrimSpace(expected))) {
+		return errors.Errorf("response content type is %q, want %q", contentType, expected)
+	}
+	return nil
+}
+
+type HttpChecker struct {
+	Resource string
+	// ExpectStatus overrides --http-expect-status when set.
+	ExpectStatus string
+	// Headers are sent with every request.
+	Headers map[string]string
+
+	// transport is kept across attempts so connections can be reused.
+	transport *http.Transport
+	// lastBody is the previous body seen with --http-stable, or the value
+	// at --http-json-path when set.
+	lastBody []byte
+	seenBody bool
+	// fromCode and fromSeen record the first --http-from-status response,
+	// and transition the completed transition.
+	fromCode   int
+	fromSeen   time.Time
+	transition *statusTransition
+	// paths has a checker per --http-path.
+	paths []*HttpChecker
+}
+
+var _ ResourceChecker = (*HttpChecker)(nil)
+
+func (h *HttpChecker) Check(ctx context.Context) error {
+	if len(httpPaths) > 0 {
+		return h.checkPaths(ctx)
+	}
+	return h.checkResource(ctx)
+}
+
+// checkResource checks h.Resource itself, ignoring --http-path.
+func (h *HttpChecker) checkResource(ctx context.Context) error {
+	if *minHealthyEndpoints > 0 || *httpAllAddresses {
+		return checkHttpEndpoints(ctx, h.Resource, h.ExpectStatus, h.Headers, *minHealthyEndpoints)
+	}
+	if h.transport == nil {
+		h.transport = newHttpCheckTransport()
+	}
+	expectStatus := h.ExpectStatus
+	var checkResponse func(*http.Response, []byte) error
+	if *httpStable {
+		checkResponse = h.checkStable
+	}
+	if *httpFromStatus != "" {
+		// Both ends of the transition get past the status check, and
+		// checkTransition tells them apart.
+		expectStatus = *httpFromStatus + "," + *httpToStatus
+		checkResponse = h.checkTransition
+	}
+	return checkHttpResource(ctx, h.transport, h.Resource, expectStatus, h.Headers, checkResponse)
+}
+
+// checkStable fails whenever the body differs from the previous successful
+// attempt's, so --repeated-successes counts identical responses. With
+// --http-json-path only the value found there is compared, so fields such as
+// timestamps elsewhere in the body may change.
+func (h *HttpChecker) checkStable(_ *http.Response, body []byte) error {
+	if *httpJSONPath != "" {
+		value, err := lookupJSONPath(body, *httpJSONPath)
+		if err != nil {
+			return err
+		}
+		// Re-encoding ignores formatting and key order within the value.
+		if body, err = json.Marshal(value); err != nil {
+			return errors.Wrapf(err, "failed to encode %s", *httpJSONPath)
+		}
+		logVerbose("%s returned %s at %s", redactResource(h.Resource), bodySnippet(body), *httpJSONPath)
+	} else {
+		logVerbose("%s returned %s", redactResource(h.Resource), bodySnippet(body))
+	}
+	previous, seen := h.lastBody, h.seenBody
+	h.lastBody, h.seenBody = body, true
+	if seen && !bytes.Equal(previous, body) {
+		return errors.Errorf("response changed from %s to %s", bodySnippet(previous), bodySnippet(body))
+	}
+	return nil
+}
+
+// bodySnippet quotes the start of a body for messages.
+func bodySnippet(body []byte) string {
+	const max = 64
+	if len(body) > max {
+		return strconv.Quote(string(body[:max])) + "..."
+	}
+	return strconv.Quote(string(body))
+}
+
+// Close releases the connections kept between attempts.
+func (h *HttpChecker) Close() error {
+	if h.transport != nil {
+		h.transport.CloseIdleConnections()
+	}
+	for _, checker := range h.paths {
+		_ = checker.Close()
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// compressedServer serves body compressed with encoding, without being asked
+// for it, so the transport does not decompress it itself.
+func compressedServer(t *testing.T, encoding string, body func() string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		switch encoding {
+		case "gzip":
+			zw := gzip.NewWriter(&buf)
+			_, _ = zw.Write([]byte(body()))
+			_ = zw.Close()
+		case "deflate":
+			zw := zlib.NewWriter(&buf)
+			_, _ = zw.Write([]byte(body()))
+			_ = zw.Close()
+		}
+		w.Header().Set("Content-Encoding", encoding)
+		_, _ = w.Write(buf.Bytes())
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestReadHttpBodyDecompressionErrors(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, _ = zw.Write([]byte(`{"status":"ok"}`))
+	_ = zw.Close()
+	valid := buf.Bytes()
+	corrupt := bytes.Clone(valid)
+	// The last 8 bytes hold the CRC-32 and size of the uncompressed data.
+	corrupt[len(corrupt)-8] ^= 0xff
+
+	tests := []struct {
+		name string
+		body []byte
+		want string
+	}{
+		{"truncated", valid[:len(valid)-4], "response body truncated"},
+		{"bad checksum", corrupt, "failed to decompress response body"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				Header: http.Header{"Content-Encoding": []string{"gzip"}},
+				Body:   io.NopCloser(bytes.NewReader(tt.body)),
+			}
+			_, err := readHttpBody(resp)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("readHttpBody() = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestHttpTransitionWithPaths(t *testing.T) {
+	var ready sync.Map
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := ready.Load(r.URL.Path); ok {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(srv.Close)
+	setFlag(t, &httpPaths, repeatedFlag{"/a", "/b"})
+	setFlag(t, httpFromStatus, "503")
+	setFlag(t, httpToStatus, "200")
+
+	checker := &HttpChecker{Resource: srv.URL}
+	for _, path := range []string{"", "/b"} {
+		ready.Store(path, true)
+		if err := checker.Check(context.Background()); err == nil {
+			t.Fatalf("Check() = nil with /a still returning 503, want an error")
+		}
+		if result := withTransition(resourceResult{}, checker); result.Transition != nil {
+			t.Errorf("transition = %+v before every path transitioned, want nil", result.Transition)
+		}
+	}
+	ready.Store("/a", true)
+	if err := checker.Check(context.Background()); err != nil {
+		t.Fatalf("Check() = %v, want nil", err)
+	}
+	result := withTransition(resourceResult{}, checker)
+	if result.Transition == nil || result.Transition.From != 503 || result.Transition.To != 200 {
+		t.Errorf("transition = %+v, want 503 to 200", result.Transition)
+	}
+}
+
+func setFlag[T any](t *testing.T, p *T, value T) {
+	t.Helper()
+	previous := *p
+	*p = value
+	t.Cleanup(func() { *p = previous })
+}
+
+func TestHttpJSONPathDecompressesBody(t *testing.T) {
+	for _, encoding := range []string{"gzip", "deflate"} {
+		t.Run(encoding, func(t *testing.T) {
+			srv := compressedServer(t, encoding, func() string { return `{"status":{"uptime":90}}` })
+			setFlag(t, httpJSONPath, "status.uptime")
+			setFlag(t, httpMinUptime, time.Minute)
+
+			checker := &HttpChecker{Resource: srv.URL}
+			defer checker.Close()
+			if err := checker.Check(context.Background()); err != nil {
+				t.Fatalf("Check() = %v, want nil", err)
+			}
+
+			setFlag(t, httpMinUptime, 2*time.Minute)
+			if err := checker.Check(context.Background()); err == nil {
+				t.Fatal("Check() = nil, want an error for an uptime below --http-min-uptime")
+			}
+		})
+	}
+}
+
+func TestHttpStableComparesDecompressedBody(t *testing.T) {
+	bodies := []string{"one", "one", "two"}
+	n := 0
+	srv := compressedServer(t, "gzip", func() string {
+		body := bodies[n]
+		n++
+		return body
+	})
+	setFlag(t, httpStable, true)
+
+	checker := &HttpChecker{Resource: srv.URL}
+	defer checker.Close()
+	for i, wantErr := range []bool{false, false, true} {
+		err := checker.Check(context.Background())
+		if (err != nil) != wantErr {
+			t.Fatalf("attempt %d: Check() = %v, want error %v", i+1, err, wantErr)
+		}
+	}
+	if string(checker.lastBody) != "two" {
+		t.Errorf("lastBody = %q, want the decompressed %q", checker.lastBody, "two")
+	}
+}
+
+func TestHttpStableComparesJSONPathValue(t *testing.T) {
+	bodies := []string{
+		`{"status":"ok","time":1}`,
+		`{"time":2, "status":"ok"}`,
+		`{"status":"ok","time":3,"request_id":"b"}`,
+		`{"status":"degraded","time":4}`,
+	}
+	n := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(bodies[n]))
+		n++
+	}))
+	defer srv.Close()
+	setFlag(t, httpStable, true)
+	setFlag(t, httpJSONPath, "status")
+
+	checker := &HttpChecker{Resource: srv.URL}
+	defer checker.Close()
+	for i, wantErr := range []bool{false, false, false, true} {
+		err := checker.Check(context.Background())
+		if (err != nil) != wantErr {
+			t.Fatalf("attempt %d: Check() = %v, want error %v", i+1, err, wantErr)
+		}
+	}
+}
+
+func TestSuccessScriptReceivesDecompressedBody(t *testing.T) {
+	srv := compressedServer(t, "gzip", func() string { return "ready" })
+	out := filepath.Join(t.TempDir(), "stdin")
+	script := filepath.Join(t.TempDir(), "script.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ncat > "+out+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	setFlag(t, successScript, script)
+
+	checker := &HttpChecker{Resource: srv.URL}
+	defer checker.Close()
+	if err := checker.Check(context.Background()); err != nil {
+		t.Fatalf("Check() = %v, want nil", err)
+	}
+	stdin, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "200\nready"; string(stdin) != want {
+		t.Errorf("script stdin = %q, want %q", stdin, want)
+	}
+}
+
+// TestHttpRetriesUntilCertificateIsServed covers a server that answers TLS
+// handshakes before it has a certificate to present, as during a rotation.
+func TestHttpRetriesUntilCertificateIsServed(t *testing.T) {
+	var hasCert atomic.Bool
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+	// GetCertificate is only asked when there are no Certificates to fall
+	// back on, so the test certificate moves into it.
+	cert := srv.TLS.Certificates[0]
+	srv.TLS.Certificates = nil
+	srv.TLS.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		if !hasCert.Load() {
+			return nil, errors.New("no certificate yet")
+		}
+		return &cert, nil
+	}
+
+	checker := &HttpChecker{Resource: srv.URL}
+	checker.transport = newHttpCheckTransport()
+	checker.transport.TLSClientConfig = srv.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+
+	var results []CheckResult
+	_, err := watchResource(context.Background(), resourceSpec{Name: "test"}, &swapChecker{
+		ResourceChecker: checker,
+		// The certificate appears before the third attempt.
+		before: func(attempt int) { hasCert.Store(attempt >= 3) },
+	}, waitOptions{
+		SuccessThreshold: 1,
+		FailureThreshold: 1,
+		Budget:           &attemptBudget{limit: 5},
+		Clock:            newFakeClock(),
+	}, func(result CheckResult) {
+		results = append(results, result)
+	})
+	if err != nil {
+		t.Fatalf("watchResource() = %v, want nil", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("took %d attempts, want 3", len(results))
+	}
+	for _, result := range results[:2] {
+		if result.Err == nil || !strings.Contains(result.Err.Error(), "tls handshake failed") {
+			t.Errorf("attempt %d: error = %v, want a tls handshake failure", result.Attempt, result.Err)
+		}
+		if isConfigError(result.Err) {
+			t.Errorf("attempt %d: %v classified as a configuration error", result.Attempt, result.Err)
+		}
+	}
+}
+
+// swapChecker calls before with the attempt number ahead of every check.
+type swapChecker struct {
+	ResourceChecker
+	before   func(attempt int)
+	attempts int
+}
+
+func (c *swapChecker) Check(ctx context.Context) error {
+	c.attempts++
+	c.before(c.attempts)
+	return c.ResourceChecker.Check(ctx)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// decodedBody returns the response body with any gzip or deflate
+// Content-Encoding removed. The transport already does this for gzip when it
+// negotiated compression itself (resp.Uncompressed), but not for deflate or
+// for servers that compress without being asked.
+func decodedBody(resp *http.Response) (io.Reader, error) {
+	if resp.Uncompressed {
+		return resp.Body, nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+		return resp.Body, nil
+	case "gzip", "x-gzip":
+		r, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decompress gzip response body")
+		}
+		return r, nil
+	case "deflate":
+		// "deflate" is meant to be zlib-wrapped, but some servers send a raw
+		// deflate stream, so sniff the zlib header first.
+		br := bufio.NewReader(resp.Body)
+		header, _ := br.Peek(2)
+		if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			r, err := zlib.NewReader(br)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to decompress deflate response body")
+			}
+			return r, nil
+		}
+		return flate.NewReader(br), nil
+	default:
+		return nil, errors.Errorf("unsupported response content encoding %q", resp.Header.Get("Content-Encoding"))
+	}
+}
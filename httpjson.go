@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	httpJSONPath  = flag.String("http-json-path", "", "Dot-separated path of a value the JSON body of HTTP resources must contain, e.g. status.uptime; array elements are selected by index")
+	httpMinUptime = flag.Duration("http-min-uptime", 0, "Require the value at --http-json-path, in seconds or as a duration such as 1m30s, to be at least this uptime")
+)
+
+func validateJSONPathFlags() error {
+	if *httpMinUptime < 0 {
+		return errors.New("--http-min-uptime must not be negative")
+	}
+	if *httpMinUptime > 0 && *httpJSONPath == "" {
+		return errors.New("--http-min-uptime requires --http-json-path to point at the uptime")
+	}
+	if *httpJSONPath != "" && (strings.HasPrefix(*httpJSONPath, ".") || strings.HasSuffix(*httpJSONPath, ".") || strings.Contains(*httpJSONPath, "..")) {
+		return errors.Errorf("invalid --http-json-path %q", *httpJSONPath)
+	}
+	return nil
+}
+
+// lookupJSONPath walks path through the decoded body. Bodies that are not JSON
+// yet, or lack the value, are retried, since a starting service often answers
+// with an error page first.
+func lookupJSONPath(body []byte, path string) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, errors.Wrapf(err, "response body %s is not JSON", bodySnippet(body))
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			next, ok := v[key]
+			if !ok {
+				return nil, errors.Errorf("response has no %s", path)
+			}
+			value = next
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, errors.Errorf("response has no %s", path)
+			}
+			value = v[i]
+		default:
+			return nil, errors.Errorf("response has no %s", path)
+		}
+	}
+	if value == nil {
+		return nil, errors.Errorf("%s is null", path)
+	}
+	return value, nil
+}
+
+// parseUptime accepts a number of seconds, or a duration string.
+func parseUptime(value interface{}) (time.Duration, error) {
+	switch v := value.(type) {
+	case json.Number:
+		seconds, err := v.Float64()
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(seconds * float64(time.Second)), nil
+	case string:
+		if seconds, err := strconv.ParseFloat(v, 64); err == nil {
+			return time.Duration(seconds * float64(time.Second)), nil
+		}
+		return time.ParseDuration(v)
+	case map[string]interface{}:
+		return 0, errors.New("found an object")
+	case []interface{}:
+		return 0, errors.New("found an array")
+	default:
+		return 0, errors.Errorf("found %v", value)
+	}
+}
+
+// checkJSONPath requires the body to hold --http-json-path and, with
+// --http-min-uptime, the uptime found there to be long enough.
+func checkJSONPath(resource string, body []byte) error {
+	value, err := lookupJSONPath(body, *httpJSONPath)
+	if err != nil {
+		return err
+	}
+	if *httpMinUptime == 0 {
+		return nil
+	}
+	uptime, err := parseUptime(value)
+	if err != nil {
+		return newConfigError(errors.Wrapf(err, "%s is not an uptime in seconds or a duration", *httpJSONPath))
+	}
+	logVerbose("%s reports an uptime of %s", redactResource(resource), uptime)
+	if uptime < *httpMinUptime {
+		return errors.Errorf("uptime %s is below --http-min-uptime %s", uptime, *httpMinUptime)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	httpPaths repeatedFlag
+)
+
+func init() {
+	flag.Var(&httpPaths, "http-path", "Check this path of every HTTP resource instead of the resource's own, e.g. /healthz; when repeated, every path must pass (repeatable)")
+}
+
+// httpPathCheckers returns a checker per --http-path, resolved against the
+// resource like a link, so query strings and relative paths work as well.
+func (h *HttpChecker) httpPathCheckers() ([]*HttpChecker, error) {
+	base, err := url.Parse(h.Resource)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse url")
+	}
+	checkers := make([]*HttpChecker, len(httpPaths))
+	for i, path := range httpPaths {
+		ref, err := url.Parse(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid --http-path %q", path)
+		}
+		checkers[i] = &HttpChecker{
+			Resource:     base.ResolveReference(ref).String(),
+			ExpectStatus: h.ExpectStatus,
+			Headers:      h.Headers,
+		}
+	}
+	return checkers, nil
+}
+
+// checkPaths checks every --http-path, reporting each with --verbose, and
+// fails unless all of them pass.
+func (h *HttpChecker) checkPaths(ctx context.Context) error {
+	if h.paths == nil {
+		paths, err := h.httpPathCheckers()
+		if err != nil {
+			return newConfigError(err)
+		}
+		h.paths = paths
+	}
+
+	failed := 0
+	var lastErr error
+	var lastPath string
+	for i, checker := range h.paths {
+		if err := checker.checkResource(ctx); err != nil {
+			logVerbose("%s: path %s is not ready: %v", redactResource(h.Resource), httpPaths[i], err)
+			failed++
+			lastErr, lastPath = err, httpPaths[i]
+			continue
+		}
+		logVerbose("%s: path %s is ready", redactResource(h.Resource), httpPaths[i])
+	}
+	if lastErr == nil {
+		return nil
+	}
+	return errors.Wrapf(lastErr, "%d of %d paths failed; %s", failed, len(h.paths), lastPath)
+}
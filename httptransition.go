@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	httpFromStatus = flag.String("http-from-status", "", "Comma-separated HTTP status codes an HTTP resource must return before --http-to-status counts, e.g. 503 to wait for a rollout to actually happen")
+	httpToStatus   = flag.String("http-to-status", "", "Comma-separated HTTP status codes that count as available once --http-from-status was seen")
+)
+
+// statusTransition is the change from --http-from-status to --http-to-status
+// observed by an HttpChecker.
+type statusTransition struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+	// Seconds is the time from the first "from" response to the first "to"
+	// one after it.
+	Seconds float64 `json:"seconds"`
+}
+
+func validateTransitionFlags() error {
+	if (*httpFromStatus == "") != (*httpToStatus == "") {
+		return errors.New("--http-from-status and --http-to-status must be used together")
+	}
+	if *httpFromStatus == "" {
+		return nil
+	}
+	if *minHealthyEndpoints > 0 || *httpAllAddresses {
+		return errors.New("--http-from-status cannot be combined with --min-healthy-endpoints or --http-all-addresses")
+	}
+	from, err := parseStatusList(*httpFromStatus)
+	if err != nil {
+		return errors.Wrap(err, "invalid --http-from-status")
+	}
+	to, err := parseStatusList(*httpToStatus)
+	if err != nil {
+		return errors.Wrap(err, "invalid --http-to-status")
+	}
+	for code := range from {
+		if to[code] {
+			return errors.Errorf("status code %d cannot be both in --http-from-status and --http-to-status", code)
+		}
+	}
+	return nil
+}
+
+// checkTransition fails until a "from" status was seen, and then passes
+// "to" statuses. A "to" status seen first is treated as stale and retried.
+func (h *HttpChecker) checkTransition(resp *http.Response, body []byte) error {
+	code := resp.StatusCode
+	from, _ := parseStatusList(*httpFromStatus)
+	if from[code] {
+		if h.fromSeen.IsZero() {
+			logVerbose("%s returned %d, waiting for %s", redactResource(h.Resource), code, *httpToStatus)
+			h.fromCode, h.fromSeen = code, time.Now()
+		}
+		return errors.Errorf("status code %d, waiting for %s", code, *httpToStatus)
+	}
+	if h.fromSeen.IsZero() {
+		return errors.Errorf("status code %d before any of %s was seen", code, *httpFromStatus)
+	}
+	if h.transition == nil {
+		h.transition = &statusTransition{From: h.fromCode, To: code, Seconds: time.Since(h.fromSeen).Seconds()}
+		logVerbose("%s went from %d to %d in %s", redactResource(h.Resource), h.fromCode, code, time.Since(h.fromSeen).Round(time.Millisecond))
+	}
+	if *httpStable {
+		return h.checkStable(resp, body)
+	}
+	return nil
+}
+
+// observedTransition returns the transition seen by the checker, if any. With
+// --http-path the resource only transitioned once every path did, and the
+// path that completed its transition last is reported.
+func (h *HttpChecker) observedTransition() *statusTransition {
+	if len(h.paths) == 0 {
+		return h.transition
+	}
+	var last *statusTransition
+	var lastAt time.Time
+	for _, checker := range h.paths {
+		if checker.transition == nil {
+			return nil
+		}
+		at := checker.fromSeen.Add(time.Duration(checker.transition.Seconds * float64(time.Second)))
+		if last == nil || at.After(lastAt) {
+			last, lastAt = checker.transition, at
+		}
+	}
+	return last
+}
+
+// withTransition adds the transition observed by checker, if any, to result.
+func withTransition(result resourceResult, checker ResourceChecker) resourceResult {
+	if t, ok := checker.(interface{ observedTransition() *statusTransition }); ok {
+		result.Transition = t.observedTransition()
+	}
+	return result
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	influxToken = flag.String("influx-token", "", "Token sent in the Authorization header when checking InfluxDB resources")
+)
+
+func isInfluxResource(resource string) bool {
+	return strings.HasPrefix(resource, "influxdb://") || strings.HasPrefix(resource, "influxdbs://")
+}
+
+// influxHealthURL maps influxdb://host:port to the plain HTTP health endpoint
+// and influxdbs://host:port to the HTTPS one.
+func influxHealthURL(resource string) string {
+	if strings.HasPrefix(resource, "influxdbs://") {
+		return "https://" + strings.TrimPrefix(resource, "influxdbs://") + "/health"
+	}
+	return "http://" + strings.TrimPrefix(resource, "influxdb://") + "/health"
+}
+
+func checkInfluxResource(ctx context.Context, cx *http.Client, resource string) error {
+	cappedCtx, cancel := context.WithTimeout(ctx, checkTimeout(ctx))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(cappedCtx, "GET", influxHealthURL(resource), nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+	if *influxToken != "" {
+		req.Header.Set("Authorization", "Token "+*influxToken)
+	}
+
+	resp, err := cx.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to perform request")
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	// InfluxDB answers 503 with a "fail" body while unhealthy, so decode the
+	// body regardless of the status code.
+	var health struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+	}
+	body, err := decodedBody(resp)
+	if err != nil {
+		return err
+	}
+	if err := json.NewDecoder(body).Decode(&health); err != nil {
+		return errors.Wrapf(err, "failed to decode health response (status code %d)", resp.StatusCode)
+	}
+
+	if health.Status != "pass" {
+		return errors.Errorf("influxdb health status is %q: %s", health.Status, health.Message)
+	}
+
+	return nil
+}
+
+type InfluxChecker struct {
+	Resource string
+
+	// client is kept across attempts so connections can be reused.
+	client *http.Client
+}
+
+var _ ResourceChecker = (*InfluxChecker)(nil)
+
+func (i *InfluxChecker) Check(ctx context.Context) error {
+	if i.client == nil {
+		i.client = newHttpClient(newHttpTransport())
+	}
+	return checkInfluxResource(ctx, i.client, i.Resource)
+}
+
+// Close releases the connections kept between attempts.
+func (i *InfluxChecker) Close() error {
+	if i.client != nil {
+		i.client.CloseIdleConnections()
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+var (
+	k8sMinReady = flag.Int("k8s-min-ready", 1, "Number of ready endpoints required for k8s:// resources")
+)
+
+func isK8sResource(resource string) bool {
+	return strings.HasPrefix(resource, "k8s://")
+}
+
+// k8sConfig is the subset of client configuration needed to read
+// EndpointSlices from the API server.
+type k8sConfig struct {
+	Server    string
+	Token     string
+	TLSConfig *tls.Config
+}
+
+func loadK8sConfig() (*k8sConfig, error) {
+	if host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT"); host != "" && port != "" {
+		return loadInClusterK8sConfig(host, port)
+	}
+
+	path := os.Getenv("KUBECONFIG")
+	if path != "" {
+		// Only the first entry of a KUBECONFIG list is consulted.
+		path = filepath.SplitList(path)[0]
+	} else {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to locate kubeconfig")
+		}
+		path = filepath.Join(home, ".kube", "config")
+	}
+	return loadKubeconfig(path)
+}
+
+func loadInClusterK8sConfig(host, port string) (*k8sConfig, error) {
+	token, err := os.ReadFile(filepath.Join(serviceAccountDir, "token"))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read service account token")
+	}
+
+	caPEM, err := os.ReadFile(filepath.Join(serviceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read service account CA")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New("failed to parse service account CA")
+	}
+
+	return &k8sConfig{
+		Server:    "https://" + net.JoinHostPort(host, port),
+		Token:     strings.TrimSpace(string(token)),
+		TLSConfig: &tls.Config{RootCAs: pool},
+	}, nil
+}
+
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster string `yaml:"cluster"`
+			User    string `yaml:"user"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string      `yaml:"token"`
+			TokenFile             string      `yaml:"tokenFile"`
+			ClientCertificate     string      `yaml:"client-certificate"`
+			ClientCertificateData string      `yaml:"client-certificate-data"`
+			ClientKey             string      `yaml:"client-key"`
+			ClientKeyData         string      `yaml:"client-key-data"`
+			Exec                  interface{} `yaml:"exec"`
+			AuthProvider          interface{} `yaml:"auth-provider"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+}
+
+// kubeconfigBytes returns inline base64 data if present, otherwise the
+// contents of the referenced file.
+func kubeconfigBytes(data, file string) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if file != "" {
+		return os.ReadFile(file)
+	}
+	return nil, nil
+}
+
+// loadKubeconfig supports static tokens and client certificates. Exec and
+// auth-provider plugins are not supported.
+func loadKubeconfig(path string) (*k8sConfig, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read kubeconfig")
+	}
+
+	var kc kubeconfig
+	if err := yaml.Unmarshal(raw, &kc); err != nil {
+		return nil, errors.Wrap(err, "failed to parse kubeconfig")
+	}
+
+	var clusterName, userName string
+	for _, c := range kc.Contexts {
+		if c.Name == kc.CurrentContext {
+			clusterName, userName = c.Context.Cluster, c.Context.User
+		}
+	}
+	if clusterName == "" {
+		return nil, errors.Errorf("kubeconfig context %q not found", kc.CurrentContext)
+	}
+
+	cfg := &k8sConfig{TLSConfig: &tls.Config{}}
+	for _, c := range kc.Clusters {
+		if c.Name != clusterName {
+			continue
+		}
+		cfg.Server = c.Cluster.Server
+		cfg.TLSConfig.InsecureSkipVerify = c.Cluster.InsecureSkipTLSVerify
+		caPEM, err := kubeconfigBytes(c.Cluster.CertificateAuthorityData, c.Cluster.CertificateAuthority)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load cluster CA")
+		}
+		if caPEM != nil {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(caPEM) {
+				return nil, errors.New("failed to parse cluster CA")
+			}
+			cfg.TLSConfig.RootCAs = pool
+		}
+	}
+	if cfg.Server == "" {
+		return nil, errors.Errorf("kubeconfig cluster %q not found", clusterName)
+	}
+
+	for _, u := range kc.Users {
+		if u.Name != userName {
+			continue
+		}
+		if u.User.Exec != nil || u.User.AuthProvider != nil {
+			return nil, errors.Errorf("kubeconfig user %q uses an unsupported credential plugin", userName)
+		}
+		cfg.Token = u.User.Token
+		if cfg.Token == "" && u.User.TokenFile != "" {
+			token, err := os.ReadFile(u.User.TokenFile)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to read token file")
+			}
+			cfg.Token = strings.TrimSpace(string(token))
+		}
+		certPEM, err := kubeconfigBytes(u.User.ClientCertificateData, u.User.ClientCertificate)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load client certificate")
+		}
+		keyPEM, err := kubeconfigBytes(u.User.ClientKeyData, u.User.ClientKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load client key")
+		}
+		if certPEM != nil && keyPEM != nil {
+			cert, err := tls.X509KeyPair(certPEM, keyPEM)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to parse client certificate")
+			}
+			cfg.TLSConfig.Certificates = []tls.Certificate{cert}
+		}
+	}
+
+	return cfg, nil
+}
+
+// parseK8sResource splits k8s://namespace/service into its parts.
+func parseK8sResource(resource string) (string, string, error) {
+	parts := strings.Split(strings.TrimPrefix(resource, "k8s://"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", errors.Errorf("expected k8s://namespace/service, got %s", resource)
+	}
+	return parts[0], parts[1], nil
+}
+
+func checkK8sResource(ctx context.Context, cfg *k8sConfig, cx *http.Client, resource string) error {
+	cappedCtx, cancel := context.WithTimeout(ctx, checkTimeout(ctx))
+	defer cancel()
+
+	namespace, service, err := parseK8sResource(resource)
+	if err != nil {
+		return newConfigError(err)
+	}
+
+	endpoint := strings.TrimSuffix(cfg.Server, "/") +
+		"/apis/discovery.k8s.io/v1/namespaces/" + url.PathEscape(namespace) +
+		"/endpointslices?labelSelector=" + url.QueryEscape("kubernetes.io/service-name="+service)
+	req, err := http.NewRequestWithContext(cappedCtx, "GET", endpoint, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+	if cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+cfg.Token)
+	}
+
+	resp, err := cx.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to query kubernetes api")
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		return newAuthError(errors.New("kubernetes api rejected the credentials"))
+	case http.StatusForbidden:
+		return newConfigError(errors.Errorf("forbidden from listing endpointslices in namespace %s; grant list on endpointslices.discovery.k8s.io", namespace))
+	default:
+		return errors.Errorf("unexpected status code %d listing endpointslices", resp.StatusCode)
+	}
+
+	var slices struct {
+		Items []struct {
+			Endpoints []struct {
+				Addresses  []string `json:"addresses"`
+				Conditions struct {
+					Ready *bool `json:"ready"`
+				} `json:"conditions"`
+			} `json:"endpoints"`
+		} `json:"items"`
+	}
+	body, err := decodedBody(resp)
+	if err != nil {
+		return err
+	}
+	if err := json.NewDecoder(body).Decode(&slices); err != nil {
+		return errors.Wrap(err, "failed to decode endpointslices")
+	}
+
+	ready := 0
+	for _, slice := range slices.Items {
+		for _, ep := range slice.Endpoints {
+			// A nil ready condition should be interpreted as ready.
+			if ep.Conditions.Ready == nil || *ep.Conditions.Ready {
+				ready += len(ep.Addresses)
+			}
+		}
+	}
+	if ready < *k8sMinReady {
+		return errors.Errorf("service %s/%s has %d ready endpoints, want %d", namespace, service, ready, *k8sMinReady)
+	}
+
+	return nil
+}
+
+type K8sEndpointsChecker struct {
+	Resource string
+
+	// client is kept across attempts so connections can be reused.
+	client *http.Client
+}
+
+var _ ResourceChecker = (*K8sEndpointsChecker)(nil)
+
+func (k *K8sEndpointsChecker) Check(ctx context.Context) error {
+	// The config is loaded for every attempt, since a token may be rotated
+	// while waiting, but the transport is built from the first one.
+	cfg, err := loadK8sConfig()
+	if err != nil {
+		return newConfigError(err)
+	}
+	if k.client == nil {
+		transport := newHttpTransport()
+		transport.TLSClientConfig = cfg.TLSConfig
+		k.client = newHttpClient(transport)
+	}
+	return checkK8sResource(ctx, cfg, k.client, k.Resource)
+}
+
+// Close releases the connections kept between attempts.
+func (k *K8sEndpointsChecker) Close() error {
+	if k.client != nil {
+		k.client.CloseIdleConnections()
+	}
+	return nil
+}
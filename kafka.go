@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/segmentio/kafka-go"
+)
+
+var (
+	kafkaGroup = flag.String("kafka-group", "", "Also require this consumer group of kafka:// resources to be stable and to have committed offsets")
+)
+
+func isKafkaResource(resource string) bool {
+	return strings.HasPrefix(resource, "kafka://") || strings.HasPrefix(resource, "kafkas://")
+}
+
+// kafkaBrokers splits kafka://host1:port,host2:port into addresses,
+// defaulting to port 9092.
+func kafkaBrokers(resource string) []string {
+	hosts := strings.TrimPrefix(strings.TrimPrefix(resource, "kafkas://"), "kafka://")
+	if i := strings.IndexAny(hosts, "/?"); i != -1 {
+		hosts = hosts[:i]
+	}
+
+	var brokers []string
+	for _, host := range strings.Split(hosts, ",") {
+		if host == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(host, "9092")
+		}
+		brokers = append(brokers, host)
+	}
+	return brokers
+}
+
+// checkKafkaResource waits for the cluster to return metadata with at least
+// one broker, and with --kafka-group, for the group to be stable and to have
+// committed offsets.
+func checkKafkaResource(ctx context.Context, resource string) error {
+	cappedCtx, cancel := context.WithTimeout(ctx, checkTimeout(ctx))
+	defer cancel()
+
+	brokers := kafkaBrokers(resource)
+	if len(brokers) == 0 {
+		return newConfigError(errors.New("at least one kafka broker is required"))
+	}
+
+	transport := &kafka.Transport{Dial: dialContext}
+	if strings.HasPrefix(resource, "kafkas://") {
+		transport.TLS = &tls.Config{}
+	}
+	defer transport.CloseIdleConnections()
+	client := &kafka.Client{Addr: kafka.TCP(brokers...), Transport: transport}
+
+	metadata, err := client.Metadata(cappedCtx, &kafka.MetadataRequest{Topics: []string{}})
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch kafka metadata")
+	}
+	if len(metadata.Brokers) == 0 {
+		return errors.New("kafka cluster reports no brokers")
+	}
+	logVerbose("kafka cluster %s has %d brokers", metadata.ClusterID, len(metadata.Brokers))
+
+	if *kafkaGroup == "" {
+		return nil
+	}
+	return checkKafkaGroup(cappedCtx, client, *kafkaGroup)
+}
+
+// checkKafkaGroup asks the group's coordinator for its state. Missing groups
+// are reported as Dead, and rebalancing ones as PreparingRebalance or
+// CompletingRebalance; both are retried.
+func checkKafkaGroup(ctx context.Context, client *kafka.Client, group string) error {
+	resp, err := client.DescribeGroups(ctx, &kafka.DescribeGroupsRequest{GroupIDs: []string{group}})
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe kafka consumer group %s", group)
+	}
+	if len(resp.Groups) != 1 {
+		return errors.Errorf("kafka returned %d descriptions for consumer group %s", len(resp.Groups), group)
+	}
+	described := resp.Groups[0]
+	if described.Error != nil {
+		return errors.Wrapf(described.Error, "failed to describe kafka consumer group %s", group)
+	}
+	logVerbose("kafka consumer group %s is %s with %d members", group, described.GroupState, len(described.Members))
+	if described.GroupState != "Stable" {
+		return errors.Errorf("kafka consumer group %s is %s, want Stable", group, described.GroupState)
+	}
+	return checkKafkaGroupOffsets(ctx, client, group)
+}
+
+// checkKafkaGroupOffsets asks the group's coordinator for the offsets
+// committed on every topic. A stable group whose members have not committed
+// yet reports none, or -1 for the partitions it was assigned, and is retried.
+func checkKafkaGroupOffsets(ctx context.Context, client *kafka.Client, group string) error {
+	resp, err := client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{GroupID: group})
+	if err != nil {
+		return errors.Wrapf(err, "failed to fetch offsets of kafka consumer group %s", group)
+	}
+	if resp.Error != nil {
+		return errors.Wrapf(resp.Error, "failed to fetch offsets of kafka consumer group %s", group)
+	}
+	committed := 0
+	for _, partitions := range resp.Topics {
+		for _, partition := range partitions {
+			if partition.Error == nil && partition.CommittedOffset >= 0 {
+				committed++
+			}
+		}
+	}
+	logVerbose("kafka consumer group %s has committed offsets for %d partitions of %d topics", group, committed, len(resp.Topics))
+	if committed == 0 {
+		return errors.Errorf("kafka consumer group %s has no committed offsets yet", group)
+	}
+	return nil
+}
+
+type KafkaChecker struct {
+	Resource string
+}
+
+var _ ResourceChecker = (*KafkaChecker)(nil)
+
+func (k *KafkaChecker) Check(ctx context.Context) error {
+	return checkKafkaResource(ctx, k.Resource)
+}
@@ -0,0 +1,33 @@
+package main
+
+// Logger receives progress messages from waitForResource. The CLI passes
+// verboseLogger, which only prints them with --verbose.
+type Logger interface {
+	// Infof reports progress, such as a resource entering its hold period.
+	Infof(format string, args ...interface{})
+	// Warnf reports failed attempts.
+	Warnf(format string, args ...interface{})
+}
+
+func (o waitOptions) logger() Logger {
+	if o.Logger != nil {
+		return o.Logger
+	}
+	return nopLogger{}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Infof(string, ...interface{}) {}
+func (nopLogger) Warnf(string, ...interface{}) {}
+
+// verboseLogger is the CLI's logger: messages go to stderr with --verbose.
+type verboseLogger struct{}
+
+func (verboseLogger) Infof(format string, args ...interface{}) {
+	logVerbose(format, args...)
+}
+
+func (verboseLogger) Warnf(format string, args ...interface{}) {
+	logVerboseColored(colorYellow, format, args...)
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock advances by the requested duration whenever the wait loop waits,
+// so tests run without sleeping.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+var _ Clock = (*fakeClock)(nil)
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	ch <- c.advance(d)
+	return ch
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	return c.now
+}
+
+// sequenceChecker succeeds or fails as given by outcomes, one per attempt,
+// written as S and F. Attempts beyond them fail.
+type sequenceChecker struct {
+	outcomes string
+	attempts int
+}
+
+var _ ResourceChecker = (*sequenceChecker)(nil)
+
+func (c *sequenceChecker) Check(context.Context) error {
+	c.attempts++
+	if c.attempts <= len(c.outcomes) && c.outcomes[c.attempts-1] == 'S' {
+		return nil
+	}
+	return errors.New("not ready")
+}
+
+func TestWatchResourceFailureThreshold(t *testing.T) {
+	tests := []struct {
+		name             string
+		outcomes         string
+		successes        int
+		failureThreshold int
+		wantAttempts     int
+		wantReady        bool
+	}{
+		{"failure resets count", "SSFSSS", 3, 1, 6, true},
+		{"single failure tolerated", "SSFS", 3, 2, 4, true},
+		{"alternating failures tolerated", "SFSFS", 3, 2, 5, true},
+		{"alternating failures reset count", "SFSFSF", 3, 1, 6, false},
+		{"consecutive failures reset count", "SSFFSSS", 3, 2, 7, true},
+		{"failures below threshold before first success", "FFSSS", 3, 3, 5, true},
+		{"threshold reached after alternation", "SFSFFSS", 3, 2, 7, false},
+		{"threshold of one success", "FSF", 1, 1, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := &sequenceChecker{outcomes: tt.outcomes}
+			var results []CheckResult
+			stats, err := watchResource(context.Background(), resourceSpec{Name: "test"}, checker, waitOptions{
+				SuccessThreshold: tt.successes,
+				FailureThreshold: tt.failureThreshold,
+				Budget:           &attemptBudget{limit: len(tt.outcomes)},
+				Clock:            newFakeClock(),
+			}, func(result CheckResult) {
+				results = append(results, result)
+			})
+			if (err == nil) != tt.wantReady {
+				t.Fatalf("watchResource() = %v, want ready %v", err, tt.wantReady)
+			}
+			if stats.Attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", stats.Attempts, tt.wantAttempts)
+			}
+			if len(results) != tt.wantAttempts {
+				t.Errorf("reported %d results, want %d", len(results), tt.wantAttempts)
+			}
+			if !tt.wantReady && !strings.Contains(err.Error(), "retry budget") {
+				t.Errorf("watchResource() = %v, want the budget to run out", err)
+			}
+		})
+	}
+}
+
+func TestWatchResourceTiming(t *testing.T) {
+	second := time.Second
+	tests := []struct {
+		name         string
+		outcomes     string
+		opts         waitOptions
+		wantElapsed  []time.Duration
+		wantDuration time.Duration
+		wantReady    bool
+	}{
+		{
+			name:         "default interval",
+			outcomes:     "FFS",
+			opts:         waitOptions{SuccessThreshold: 1},
+			wantElapsed:  []time.Duration{checkInterval, 2 * checkInterval, 3 * checkInterval},
+			wantDuration: 3 * checkInterval,
+			wantReady:    true,
+		},
+		{
+			name:         "hold",
+			outcomes:     "SSSS",
+			opts:         waitOptions{SuccessThreshold: 1, Interval: second, Hold: 3 * second},
+			wantElapsed:  []time.Duration{second, 2 * second, 3 * second, 4 * second},
+			wantDuration: 4 * second,
+			wantReady:    true,
+		},
+		{
+			name:         "failure during hold restarts it",
+			outcomes:     "SSFSSSS",
+			opts:         waitOptions{SuccessThreshold: 1, Interval: second, Hold: 2 * second},
+			wantElapsed:  []time.Duration{second, 2 * second, 3 * second, 4 * second, 5 * second, 6 * second},
+			wantDuration: 6 * second,
+			wantReady:    true,
+		},
+		{
+			name:         "min wait",
+			outcomes:     "S",
+			opts:         waitOptions{SuccessThreshold: 1, Interval: second, MinWait: 5 * second},
+			wantElapsed:  []time.Duration{second},
+			wantDuration: second,
+			wantReady:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newFakeClock()
+			opts := tt.opts
+			opts.FailureThreshold = 1
+			opts.Clock = clock
+			var elapsed []time.Duration
+			stats, err := watchResource(context.Background(), resourceSpec{Name: "test"}, &sequenceChecker{outcomes: tt.outcomes}, opts, func(result CheckResult) {
+				elapsed = append(elapsed, result.Elapsed)
+			})
+			if (err == nil) != tt.wantReady {
+				t.Fatalf("watchResource() = %v, want ready %v", err, tt.wantReady)
+			}
+			if len(elapsed) != len(tt.wantElapsed) {
+				t.Fatalf("attempts at %v, want %v", elapsed, tt.wantElapsed)
+			}
+			for i := range elapsed {
+				if elapsed[i] != tt.wantElapsed[i] {
+					t.Fatalf("attempts at %v, want %v", elapsed, tt.wantElapsed)
+				}
+			}
+			if stats.Duration != tt.wantDuration {
+				t.Errorf("duration = %s, want %s", stats.Duration, tt.wantDuration)
+			}
+		})
+	}
+}
+
+// slowChecker takes latency of the fake clock's time for every check.
+type slowChecker struct {
+	clock   *fakeClock
+	latency time.Duration
+}
+
+func (c *slowChecker) Check(context.Context) error {
+	c.clock.advance(c.latency)
+	return nil
+}
+
+func TestWatchResourceLatency(t *testing.T) {
+	clock := newFakeClock()
+	var results []CheckResult
+	stats, err := watchResource(context.Background(), resourceSpec{Name: "test"}, &slowChecker{clock: clock, latency: 300 * time.Millisecond}, waitOptions{
+		SuccessThreshold: 2,
+		FailureThreshold: 1,
+		Interval:         time.Second,
+		Clock:            clock,
+	}, func(result CheckResult) {
+		results = append(results, result)
+	})
+	if err != nil {
+		t.Fatalf("watchResource() = %v, want nil", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("took %d attempts, want 2", len(results))
+	}
+	for _, result := range results {
+		if result.Latency != 300*time.Millisecond {
+			t.Errorf("attempt %d: latency = %s, want 300ms", result.Attempt, result.Latency)
+		}
+	}
+	// The next interval starts once the previous check returned.
+	if want := 2600 * time.Millisecond; stats.Duration != want {
+		t.Errorf("duration = %s, want %s", stats.Duration, want)
+	}
+}
+
+// timeoutChecker records the check timeout of every check.
+type timeoutChecker struct {
+	timeouts []time.Duration
+}
+
+func (c *timeoutChecker) Check(ctx context.Context) error {
+	c.timeouts = append(c.timeouts, checkTimeout(ctx))
+	return nil
+}
+
+func TestWatchResourceCheckTimeout(t *testing.T) {
+	setFlag(t, timeout, 10)
+	tests := []struct {
+		name         string
+		checkTimeout time.Duration
+		want         time.Duration
+	}{
+		{"--timeout", 0, 10 * time.Second},
+		{"resource timeout", 90 * time.Second, 90 * time.Second},
+		{"shorter resource timeout", 2 * time.Second, 2 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := &timeoutChecker{}
+			_, err := watchResource(context.Background(), resourceSpec{Name: "test"}, checker, waitOptions{
+				SuccessThreshold: 1,
+				FailureThreshold: 1,
+				CheckTimeout:     tt.checkTimeout,
+				ConfirmWindow:    2 * time.Second,
+				Clock:            newFakeClock(),
+			}, func(CheckResult) {})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The attempt and the checks of the confirm window.
+			if len(checker.timeouts) != 3 {
+				t.Fatalf("ran %d checks, want 3", len(checker.timeouts))
+			}
+			for i, got := range checker.timeouts {
+				if got != tt.want {
+					t.Errorf("check %d: timeout = %s, want %s", i+1, got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	manifestFile = flag.String("manifest", "", "JSON or YAML file describing each resource with its own settings, in addition to any given as arguments")
+)
+
+// manifest is the document read by --manifest. JSON is accepted as well,
+// since it is a subset of YAML.
+type manifest struct {
+	Resources []manifestEntry `yaml:"resources"`
+}
+
+// manifestEntry overrides the corresponding flags for a single resource.
+type manifestEntry struct {
+	Name     string        `yaml:"name"`
+	Resource string        `yaml:"resource"`
+	Timeout  time.Duration `yaml:"timeout"`
+	Interval time.Duration `yaml:"interval"`
+	// ExpectStatus and Headers apply to HTTP resources.
+	ExpectStatus string            `yaml:"expect_status"`
+	Headers      map[string]string `yaml:"headers"`
+	// Query applies to Postgres and other SQL resources.
+	Query string `yaml:"query"`
+}
+
+// readManifest decodes and validates a manifest, returning every problem
+// found. Warnings are left to "awfi validate".
+func readManifest(path string) ([]resourceSpec, []error) {
+	specs, errs, _ := checkManifest(path)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return specs, nil
+}
+
+// checkManifest decodes a manifest and checks every entry, returning the specs
+// along with all the errors and warnings found, located by file and line.
+// Unknown fields are rejected so that typos do not silently fall back to the
+// defaults.
+func checkManifest(path string) ([]resourceSpec, []error, []string) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, []error{errors.Wrap(err, "failed to read manifest")}, nil
+	}
+
+	var m manifest
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(&m); err != nil {
+		return nil, []error{errors.Wrapf(err, "invalid manifest %s", path)}, nil
+	}
+
+	lines := manifestEntryLines(raw)
+	var errs []error
+	var warnings []string
+	specs := make([]resourceSpec, 0, len(m.Resources))
+	names := make(map[string]int)
+	for i, entry := range m.Resources {
+		location := fmt.Sprintf("%s: resource %d", path, i+1)
+		if i < len(lines) {
+			location = fmt.Sprintf("%s:%d: resource %d", path, lines[i], i+1)
+		}
+		entries, err := entry.expand()
+		if err != nil {
+			errs = append(errs, errors.Wrap(err, location))
+			continue
+		}
+		for j, entry := range entries {
+			spec, err := entry.spec()
+			if err != nil {
+				errs = append(errs, errors.Wrap(err, location))
+				break
+			}
+			if j == 0 && spec.interval() >= spec.waitTimeout() {
+				warnings = append(warnings, fmt.Sprintf("%s: interval %s is not shorter than the timeout %s, so it is never checked", location, spec.interval(), spec.waitTimeout()))
+			}
+			if other, ok := names[spec.Name]; ok {
+				warnings = append(warnings, fmt.Sprintf("%s: name %q is also used by resource %d", location, spec.Name, other))
+			} else {
+				names[spec.Name] = i + 1
+			}
+			specs = append(specs, spec)
+		}
+	}
+	return specs, errs, warnings
+}
+
+// manifestEntryLines returns the line each entry of resources starts on.
+func manifestEntryLines(raw []byte) []int {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil || len(doc.Content) == 0 {
+		return nil
+	}
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "resources" {
+			continue
+		}
+		var lines []int
+		for _, entry := range root.Content[i+1].Content {
+			lines = append(lines, entry.Line)
+		}
+		return lines
+	}
+	return nil
+}
+
+// expand expands ranges and lists in the entry's resource, see
+// expandResource, suffixing its name with the substituted values.
+func (e manifestEntry) expand() ([]manifestEntry, error) {
+	expansions, err := expandResource(e.Resource)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]manifestEntry, len(expansions))
+	for i, x := range expansions {
+		entries[i] = e
+		entries[i].Resource = x.Resource
+		if e.Name != "" {
+			entries[i].Name = expandedName(e.Name, x.Values)
+		}
+	}
+	return entries, nil
+}
+
+func (e manifestEntry) spec() (resourceSpec, error) {
+	if e.Resource == "" {
+		return resourceSpec{}, errors.New("missing resource")
+	}
+	if e.Timeout < 0 || e.Interval < 0 {
+		return resourceSpec{}, errors.New("timeout and interval must not be negative")
+	}
+
+	checker := checkerForResource(e.Resource)
+	switch c := checker.(type) {
+	case nil:
+		return resourceSpec{}, errors.Errorf("unsupported resource type: %s", redactResource(e.Resource))
+	case *HttpChecker:
+		if e.ExpectStatus != "" {
+			if _, err := parseStatusList(e.ExpectStatus); err != nil {
+				return resourceSpec{}, errors.Wrap(err, "invalid expect_status")
+			}
+		}
+		c.ExpectStatus = e.ExpectStatus
+		c.Headers = e.Headers
+	case *PostgresChecker:
+		c.Query = e.Query
+	case *SqlChecker:
+		c.Query = e.Query
+	}
+	if _, ok := checker.(*HttpChecker); !ok && (e.ExpectStatus != "" || len(e.Headers) > 0) {
+		return resourceSpec{}, errors.New("expect_status and headers only apply to http resources")
+	}
+	switch checker.(type) {
+	case *PostgresChecker, *SqlChecker:
+	default:
+		if e.Query != "" {
+			return resourceSpec{}, errors.New("query only applies to postgres and sql resources")
+		}
+	}
+
+	name := e.Name
+	if name == "" {
+		name = redactResource(e.Resource)
+	}
+	return resourceSpec{
+		Name:     name,
+		Resource: e.Resource,
+		Timeout:  e.Timeout,
+		Interval: e.Interval,
+		Checker:  checker,
+	}, nil
+}
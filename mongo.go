@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"flag"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var (
+	mongoReplset        = flag.String("mongo-replset", "", "Require mongodb:// resources to report membership of this replica set")
+	mongoRequirePrimary = flag.Bool("mongo-require-primary", false, "Require mongodb:// resources to have an elected, writable primary")
+)
+
+func isMongoResource(resource string) bool {
+	return strings.HasPrefix(resource, "mongodb://")
+}
+
+// mongoTarget is a parsed mongodb://[user:password@]host1:port,host2:port
+// [/db][?options] resource. Credentials and the database are ignored, since
+// hello does not require authentication.
+type mongoTarget struct {
+	TLS   bool
+	Hosts []string
+}
+
+// parseMongoResource splits a resource into its hosts, defaulting to port
+// 27017. TLS is enabled by the tls or ssl option, as in the connection string
+// format. url.Parse rejects the comma-separated host list, so this is done by
+// hand like for redis:// resources.
+func parseMongoResource(resource string) (mongoTarget, error) {
+	var target mongoTarget
+	rest := strings.TrimPrefix(resource, "mongodb://")
+	if i := strings.IndexAny(rest, "/?"); i != -1 {
+		if _, query, ok := strings.Cut(rest[i:], "?"); ok {
+			options, err := url.ParseQuery(query)
+			if err != nil {
+				return target, errors.Wrap(err, "invalid mongodb options")
+			}
+			target.TLS = options.Get("tls") == "true" || options.Get("ssl") == "true"
+		}
+		rest = rest[:i]
+	}
+	if at := strings.LastIndex(rest, "@"); at != -1 {
+		rest = rest[at+1:]
+	}
+	for _, host := range strings.Split(rest, ",") {
+		if host == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(host, "27017")
+		}
+		target.Hosts = append(target.Hosts, host)
+	}
+	if len(target.Hosts) == 0 {
+		return target, errors.New("at least one mongodb host is required")
+	}
+	return target, nil
+}
+
+// mongoHello holds the fields of a hello (or legacy isMaster) reply used by
+// the checks.
+type mongoHello struct {
+	OK     float64 `bson:"ok"`
+	Code   int32   `bson:"code"`
+	ErrMsg string  `bson:"errmsg"`
+	// IsWritablePrimary is reported by hello, and IsMaster by isMaster.
+	IsWritablePrimary bool   `bson:"isWritablePrimary"`
+	IsMaster          bool   `bson:"ismaster"`
+	SetName           string `bson:"setName"`
+	Primary           string `bson:"primary"`
+}
+
+const (
+	mongoOpMsg = 2013
+	// mongoCommandNotFound is returned by servers older than 4.4.2 (and
+	// patch releases 4.2.10, 4.0.21, and 3.6.21) for hello.
+	mongoCommandNotFound = 59
+	// mongoMaxMessageSize is the default maxMessageSizeBytes.
+	mongoMaxMessageSize = 48000000
+)
+
+// mongoCommand runs command against the admin database with a single OP_MSG,
+// which every server since 3.6 understands.
+func mongoCommand(conn net.Conn, command string) (mongoHello, error) {
+	var reply mongoHello
+	doc, err := bson.Marshal(bson.D{{Key: command, Value: 1}, {Key: "$db", Value: "admin"}})
+	if err != nil {
+		return reply, errors.Wrapf(err, "failed to encode %s", command)
+	}
+	// The header (length, request ID, response to, op code) is followed by
+	// the flag bits and a single body section of kind 0.
+	msg := make([]byte, 21, 21+len(doc))
+	binary.LittleEndian.PutUint32(msg[0:], uint32(len(msg)+len(doc)))
+	binary.LittleEndian.PutUint32(msg[4:], 1)
+	binary.LittleEndian.PutUint32(msg[12:], mongoOpMsg)
+	msg = append(msg, doc...)
+	if _, err := conn.Write(msg); err != nil {
+		return reply, errors.Wrapf(err, "failed to send %s", command)
+	}
+
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return reply, errors.Wrapf(err, "failed to read %s reply", command)
+	}
+	length := int(binary.LittleEndian.Uint32(header[0:]))
+	if length < 16+5+5 || length > mongoMaxMessageSize {
+		return reply, errors.Errorf("mongodb protocol error: invalid message length %d", length)
+	}
+	if opCode := binary.LittleEndian.Uint32(header[12:]); opCode != mongoOpMsg {
+		return reply, errors.Errorf("mongodb protocol error: unexpected op code %d", opCode)
+	}
+	body := make([]byte, length-16)
+	if _, err := io.ReadFull(conn, body); err != nil {
+		return reply, errors.Wrapf(err, "failed to read %s reply", command)
+	}
+	if body[4] != 0 {
+		return reply, errors.Errorf("mongodb protocol error: unexpected section kind %d", body[4])
+	}
+	// The document carries its own length, as a checksum may follow it.
+	section := body[5:]
+	docLength := int(binary.LittleEndian.Uint32(section))
+	if docLength < 5 || docLength > len(section) {
+		return reply, errors.Errorf("mongodb protocol error: invalid document length %d", docLength)
+	}
+	if err := bson.Unmarshal(section[:docLength], &reply); err != nil {
+		return reply, errors.Wrapf(err, "mongodb protocol error: invalid %s reply", command)
+	}
+	return reply, nil
+}
+
+// mongoHelloHost connects to host and sends hello, falling back to isMaster
+// on servers that do not know hello.
+func mongoHelloHost(ctx context.Context, target mongoTarget, host string) (mongoHello, error) {
+	conn, err := dialTCP(ctx, host)
+	if err != nil {
+		return mongoHello{}, err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	if target.TLS {
+		hostname, _, _ := net.SplitHostPort(host)
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: hostname})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return mongoHello{}, errors.Wrap(err, "mongodb tls handshake failed")
+		}
+		conn = tlsConn
+	}
+
+	hello, err := mongoCommand(conn, "hello")
+	if err == nil && hello.OK == 0 && hello.Code == mongoCommandNotFound {
+		hello, err = mongoCommand(conn, "isMaster")
+	}
+	if err != nil {
+		return hello, err
+	}
+	if hello.OK == 0 {
+		return hello, errors.Errorf("mongodb hello failed: %s", hello.ErrMsg)
+	}
+	return hello, nil
+}
+
+// checkMongoHello applies --mongo-replset and --mongo-require-primary to the
+// reply of a node. A member that has not joined a replica set yet, or whose
+// set has no primary, is retried, while one of another set is a configuration
+// error.
+func checkMongoHello(hello mongoHello) error {
+	if *mongoReplset != "" {
+		switch hello.SetName {
+		case *mongoReplset:
+		case "":
+			return errors.Errorf("mongodb is not a member of replica set %s yet", *mongoReplset)
+		default:
+			return newConfigError(errors.Errorf("mongodb belongs to replica set %s, want %s", hello.SetName, *mongoReplset))
+		}
+	}
+	if *mongoRequirePrimary && !hello.IsWritablePrimary && !hello.IsMaster && hello.Primary == "" {
+		return errors.New("mongodb has no primary")
+	}
+	return nil
+}
+
+// checkMongoResource asks the listed hosts in turn, like seeds, and checks the
+// first reply.
+func checkMongoResource(ctx context.Context, resource string) error {
+	cappedCtx, cancel := context.WithTimeout(ctx, checkTimeout(ctx))
+	defer cancel()
+
+	target, err := parseMongoResource(resource)
+	if err != nil {
+		return newConfigError(err)
+	}
+
+	var lastErr error
+	for _, host := range target.Hosts {
+		hello, err := mongoHelloHost(cappedCtx, target, host)
+		if err != nil {
+			if isConfigError(err) {
+				return errors.Wrapf(err, "mongodb host %s", host)
+			}
+			logVerbose("mongodb host %s did not answer hello: %v", host, err)
+			lastErr = errors.Wrapf(err, "mongodb host %s", host)
+			continue
+		}
+		logVerbose("mongodb host %s reports replica set %q, primary %q, writable %t", host, hello.SetName, hello.Primary, hello.IsWritablePrimary || hello.IsMaster)
+		return checkMongoHello(hello)
+	}
+	return lastErr
+}
+
+type MongoChecker struct {
+	Resource string
+}
+
+var _ ResourceChecker = (*MongoChecker)(nil)
+
+func (m *MongoChecker) Check(ctx context.Context) error {
+	return checkMongoResource(ctx, m.Resource)
+}
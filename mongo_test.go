@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// newFakeMongo answers every OP_MSG command with the document returned by
+// reply for the command name.
+func newFakeMongo(t *testing.T, reply func(command string) bson.M) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveFakeMongo(conn, reply)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func serveFakeMongo(conn net.Conn, reply func(command string) bson.M) {
+	defer conn.Close()
+	for {
+		header := make([]byte, 16)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		body := make([]byte, binary.LittleEndian.Uint32(header)-16)
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return
+		}
+		var request bson.D
+		if err := bson.Unmarshal(body[5:], &request); err != nil {
+			return
+		}
+		doc, err := bson.Marshal(reply(request[0].Key))
+		if err != nil {
+			return
+		}
+		msg := make([]byte, 21, 21+len(doc))
+		binary.LittleEndian.PutUint32(msg[0:], uint32(len(msg)+len(doc)))
+		binary.LittleEndian.PutUint32(msg[8:], binary.LittleEndian.Uint32(header[4:]))
+		binary.LittleEndian.PutUint32(msg[12:], mongoOpMsg)
+		if _, err := conn.Write(append(msg, doc...)); err != nil {
+			return
+		}
+	}
+}
+
+func TestParseMongoResource(t *testing.T) {
+	tests := []struct {
+		resource string
+		want     mongoTarget
+	}{
+		{"mongodb://db", mongoTarget{Hosts: []string{"db:27017"}}},
+		{"mongodb://user:p@ss@a:1,b/app?replicaSet=rs0", mongoTarget{Hosts: []string{"a:1", "b:27017"}}},
+		{"mongodb://db/?tls=true", mongoTarget{TLS: true, Hosts: []string{"db:27017"}}},
+	}
+	for _, tt := range tests {
+		got, err := parseMongoResource(tt.resource)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseMongoResource(%q) = %+v, %v, want %+v", tt.resource, got, err, tt.want)
+		}
+	}
+	if _, err := parseMongoResource("mongodb:///app"); err == nil {
+		t.Error("parseMongoResource() accepted a resource without hosts")
+	}
+}
+
+func TestMongoReplicaSet(t *testing.T) {
+	tests := []struct {
+		name           string
+		hello          bson.M
+		replset        string
+		requirePrimary bool
+		wantErr        bool
+		wantConfig     bool
+	}{
+		{"standalone", bson.M{"ok": 1, "isWritablePrimary": true}, "", false, false, false},
+		{"matching set", bson.M{"ok": 1, "setName": "rs0"}, "rs0", false, false, false},
+		{"not initiated", bson.M{"ok": 1}, "rs0", false, true, false},
+		{"other set", bson.M{"ok": 1, "setName": "rs1"}, "rs0", false, true, true},
+		{"secondary with primary", bson.M{"ok": 1, "setName": "rs0", "primary": "a:27017"}, "rs0", true, false, false},
+		{"no primary", bson.M{"ok": 1, "setName": "rs0"}, "rs0", true, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := newFakeMongo(t, func(string) bson.M { return tt.hello })
+			setFlag(t, mongoReplset, tt.replset)
+			setFlag(t, mongoRequirePrimary, tt.requirePrimary)
+			err := checkMongoResource(context.Background(), "mongodb://"+addr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkMongoResource() = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && isConfigError(err) != tt.wantConfig {
+				t.Errorf("isConfigError(%v) = %v, want %v", err, isConfigError(err), tt.wantConfig)
+			}
+		})
+	}
+}
+
+func TestMongoFallsBackToIsMaster(t *testing.T) {
+	addr := newFakeMongo(t, func(command string) bson.M {
+		if command == "hello" {
+			return bson.M{"ok": 0, "code": mongoCommandNotFound, "errmsg": "no such command: 'hello'"}
+		}
+		return bson.M{"ok": 1, "ismaster": true}
+	})
+	setFlag(t, mongoRequirePrimary, true)
+	if err := checkMongoResource(context.Background(), "mongodb://"+addr); err != nil {
+		t.Errorf("checkMongoResource() = %v, want nil", err)
+	}
+}
+
+func TestMongoTriesNextSeed(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := ln.Addr().String()
+	_ = ln.Close()
+	up := newFakeMongo(t, func(string) bson.M { return bson.M{"ok": 1, "isWritablePrimary": true} })
+	if err := checkMongoResource(context.Background(), "mongodb://"+down+","+up); err != nil {
+		t.Errorf("checkMongoResource() = %v, want nil", err)
+	}
+}
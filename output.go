@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorReset  = "\033[0m"
+)
+
+var (
+	colorMode = flag.String("color", "auto", "Colorize output: always, never, or auto (only when writing to a terminal)")
+)
+
+func validColorMode(mode string) bool {
+	switch mode {
+	case "always", "never", "auto":
+		return true
+	default:
+		return false
+	}
+}
+
+// isTerminal reports whether w is a file attached to a character device, which
+// is a good enough approximation of a TTY without pulling in a terminal
+// library.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func colorEnabled(w io.Writer) bool {
+	switch *colorMode {
+	case "always":
+		return true
+	case "never":
+		return false
+	default:
+		return isTerminal(w)
+	}
+}
+
+func colorize(w io.Writer, color string, s string) string {
+	if !colorEnabled(w) {
+		return s
+	}
+	return color + s + colorReset
+}
+
+func printColored(w io.Writer, color string, format string, args ...interface{}) {
+	_, _ = fmt.Fprintln(w, colorize(w, color, fmt.Sprintf(format, args...)))
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"flag"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+)
+
+var (
+	pgMaxLag             = flag.Int64("pg-max-lag", -1, "Require Postgres replicas to have at most this many bytes of received WAL left to replay (-1 to disable)")
+	pgMinFreeConnections = flag.Int("pg-min-free-connections", 0, "Require Postgres to have at least this many connection slots free for non-superusers")
+	pgExpect             = flag.String("pg-expect", "", "Require the first column of the first row returned by the Postgres readiness query to equal this value, e.g. t or ready")
+)
+
+// checkPostgresExpect compares the first value of rows, as text, with
+// --pg-expect. Values are decoded rather than scanned into a fixed type, so
+// queries returning text, booleans, numerics, or NULL all work.
+func checkPostgresExpect(rows pgx.Rows, expect string) error {
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return errors.Errorf("query returned no rows, want %q", expect)
+	}
+	values, err := rows.Values()
+	if err != nil {
+		return errors.Wrap(err, "failed to decode query result")
+	}
+	if len(values) == 0 {
+		return errors.Errorf("query returned no columns, want %q", expect)
+	}
+	got, err := postgresValueString(values[0])
+	if err != nil {
+		return err
+	}
+	if got != expect {
+		return errors.Errorf("query returned %q, want %q", got, expect)
+	}
+	return nil
+}
+
+// postgresValueString formats a decoded value the way psql prints it for the
+// common types.
+func postgresValueString(value interface{}) (string, error) {
+	if n, ok := value.(pgtype.Numeric); ok && n.Status == pgtype.Present && !n.NaN && n.InfinityModifier == pgtype.None {
+		return numericString(n), nil
+	}
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return "", errors.Wrap(err, "failed to decode query result")
+		}
+		value = v
+	}
+	switch v := value.(type) {
+	case nil:
+		return "", errors.New("query returned NULL")
+	case bool:
+		if v {
+			return "t", nil
+		}
+		return "f", nil
+	case []byte:
+		return string(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// checkPostgresReplicationLag compares the received and replayed WAL
+// positions of a streaming replica.
+func checkPostgresReplicationLag(ctx context.Context, conn *pgx.Conn, maxLag int64) error {
+	var inRecovery bool
+	var lag *int64
+	err := conn.QueryRow(ctx, `SELECT pg_is_in_recovery(),
+		pg_wal_lsn_diff(pg_last_wal_receive_lsn(), pg_last_wal_replay_lsn())::bigint`).Scan(&inRecovery, &lag)
+	if err != nil {
+		return wrapPostgresQueryError(ctx, err)
+	}
+	if !inRecovery {
+		return newConfigError(errors.New("--pg-max-lag requires a replica, but the server is not in recovery"))
+	}
+	if lag == nil {
+		return errors.New("replica has not received any WAL through streaming replication")
+	}
+	logVerbose("replica has %d bytes of WAL left to replay", *lag)
+	if *lag > maxLag {
+		return errors.Errorf("replication lag is %d bytes, want at most %d", *lag, maxLag)
+	}
+	return nil
+}
+
+// checkPostgresFreeConnections counts client backends against the slots
+// available to non-superusers. The probe's own connection is not counted,
+// since it is closed right after the check.
+func checkPostgresFreeConnections(ctx context.Context, conn *pgx.Conn, minFree int) error {
+	var available, used int
+	err := conn.QueryRow(ctx, `SELECT
+		current_setting('max_connections')::int - current_setting('superuser_reserved_connections')::int,
+		(SELECT count(*) FROM pg_stat_activity WHERE backend_type = 'client backend' AND pid <> pg_backend_pid())::int`).Scan(&available, &used)
+	if err != nil {
+		return wrapPostgresQueryError(ctx, err)
+	}
+	free := available - used
+	logVerbose("postgres has %d of %d connections in use, %d free", used, available, free)
+	if free < minFree {
+		return errors.Errorf("postgres has %d free connections, want at least %d", free, minFree)
+	}
+	return nil
+}
+
+// checkPostgresVersion checks version() against --db-min-version. It serves
+// CockroachDB too, whose version() is of the form "CockroachDB CCL v23.1.11".
+func checkPostgresVersion(ctx context.Context, conn *pgx.Conn) error {
+	var version string
+	if err := conn.QueryRow(ctx, "SELECT version()").Scan(&version); err != nil {
+		return wrapPostgresQueryError(ctx, err)
+	}
+	return checkDbVersion(version)
+}
+
+// numericString prints n with as many decimals as its scale, e.g. 1.50,
+// rather than the exponent form of its driver value.
+func numericString(n pgtype.Numeric) string {
+	digits := new(big.Int).Abs(n.Int).String()
+	if n.Exp > 0 {
+		digits += strings.Repeat("0", int(n.Exp))
+	} else if n.Exp < 0 {
+		scale := int(-n.Exp)
+		if len(digits) <= scale {
+			digits = strings.Repeat("0", scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+	}
+	if n.Int.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
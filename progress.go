@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	progressFd = flag.Int("progress-fd", 0, "File descriptor inherited from the parent process, e.g. 3, to write every attempt and outcome to as newline-delimited JSON while waiting (0 to disable)")
+)
+
+// progressFile is --progress-fd, opened by validateProgressFd. It is kept
+// rather than opened again, since the finalizer of a dropped *os.File would
+// close the descriptor.
+var progressFile *os.File
+
+// validateProgressFd makes sure --progress-fd is open for writing, so a
+// supervisor that forgot to pass it is told at startup.
+func validateProgressFd() error {
+	if *progressFd == 0 {
+		return nil
+	}
+	if *progressFd < 0 {
+		return errors.Errorf("invalid --progress-fd %d", *progressFd)
+	}
+	f := os.NewFile(uintptr(*progressFd), "progress-fd")
+	if f == nil {
+		return errors.Errorf("invalid --progress-fd %d", *progressFd)
+	}
+	if _, err := f.Write(nil); err != nil {
+		return errors.Wrapf(err, "--progress-fd %d is not open for writing", *progressFd)
+	}
+	progressFile = f
+	return nil
+}
+
+// progressWriter writes the same events as the --events-file, unbuffered so
+// the supervisor sees each one as it happens, followed by a finished event.
+type progressWriter struct {
+	mu  sync.Mutex
+	f   *os.File
+	enc *json.Encoder
+}
+
+func newProgressWriter(f *os.File) *progressWriter {
+	return &progressWriter{f: f, enc: json.NewEncoder(f)}
+}
+
+func (w *progressWriter) record(resource string, result CheckResult) {
+	e := event{
+		Time:      time.Now().UTC(),
+		Resource:  resource,
+		Type:      eventType(result),
+		Attempt:   result.Attempt,
+		LatencyMs: float64(result.Latency) / float64(time.Millisecond),
+	}
+	if result.Err != nil {
+		e.Error = result.Err.Error()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	// A supervisor that went away must not fail the wait.
+	_ = w.enc.Encode(e)
+}
+
+// finish writes the overall outcome and closes the descriptor, so a reader
+// sees EOF once awfi is done with it, even under --keep-running.
+func (w *progressWriter) finish(ready bool) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_ = w.enc.Encode(struct {
+		Time  time.Time `json:"time"`
+		Type  string    `json:"type"`
+		Ready bool      `json:"ready"`
+	}{time.Now().UTC(), "finished", ready})
+	return errors.Wrap(w.f.Close(), "failed to close --progress-fd")
+}
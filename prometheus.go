@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	prometheusJob      = flag.String("prometheus-job", "", "Scrape job whose targets must be up for prometheus:// resources")
+	prometheusInstance = flag.String("prometheus-instance", "", "Only consider the target with this instance label for prometheus:// resources")
+)
+
+func isPrometheusResource(resource string) bool {
+	return strings.HasPrefix(resource, "prometheus://") || strings.HasPrefix(resource, "prometheuss://")
+}
+
+// prometheusAPIURL maps prometheus://host:port to the plain HTTP API and
+// prometheuss://host:port to the HTTPS one.
+func prometheusAPIURL(resource string) string {
+	if strings.HasPrefix(resource, "prometheuss://") {
+		return "https://" + strings.TrimPrefix(resource, "prometheuss://") + "/api/v1/query"
+	}
+	return "http://" + strings.TrimPrefix(resource, "prometheus://") + "/api/v1/query"
+}
+
+// prometheusUpQuery selects the up series of the configured targets.
+func prometheusUpQuery(job, instance string) string {
+	matchers := fmt.Sprintf("job=%q", job)
+	if instance != "" {
+		matchers += fmt.Sprintf(",instance=%q", instance)
+	}
+	return "up{" + matchers + "}"
+}
+
+func checkPrometheusResource(ctx context.Context, cx *http.Client, resource string) error {
+	cappedCtx, cancel := context.WithTimeout(ctx, checkTimeout(ctx))
+	defer cancel()
+
+	if *prometheusJob == "" {
+		return newConfigError(errors.New("prometheus resources require --prometheus-job"))
+	}
+	query := prometheusUpQuery(*prometheusJob, *prometheusInstance)
+
+	req, err := http.NewRequestWithContext(cappedCtx, "GET", prometheusAPIURL(resource)+"?query="+url.QueryEscape(query), nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+
+	resp, err := cx.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to perform request")
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	// Prometheus answers bad queries with a 400 and an error body, so decode
+	// the body regardless of the status code.
+	var result struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			ResultType string `json:"resultType"`
+			Result     []struct {
+				Metric map[string]string `json:"metric"`
+				// Value is a [timestamp, "value"] pair.
+				Value [2]interface{} `json:"value"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	body, err := decodedBody(resp)
+	if err != nil {
+		return err
+	}
+	if err := json.NewDecoder(body).Decode(&result); err != nil {
+		return errors.Wrapf(err, "failed to decode query response (status code %d)", resp.StatusCode)
+	}
+	if result.Status != "success" {
+		return errors.Errorf("prometheus query %s failed: %s", query, result.Error)
+	}
+
+	if len(result.Data.Result) == 0 {
+		return errors.Errorf("prometheus has no targets matching %s", query)
+	}
+	for _, series := range result.Data.Result {
+		value, _ := series.Value[1].(string)
+		logVerbose("prometheus target %s of job %s has up=%s", series.Metric["instance"], series.Metric["job"], value)
+		if value != "1" {
+			return errors.Errorf("prometheus target %s of job %s is down (up=%s)", series.Metric["instance"], series.Metric["job"], value)
+		}
+	}
+
+	return nil
+}
+
+// PrometheusTargetChecker waits for Prometheus to report every scrape target
+// of --prometheus-job (and --prometheus-instance) as up.
+type PrometheusTargetChecker struct {
+	Resource string
+
+	// client is kept across attempts so connections can be reused.
+	client *http.Client
+}
+
+var _ ResourceChecker = (*PrometheusTargetChecker)(nil)
+
+func (p *PrometheusTargetChecker) Check(ctx context.Context) error {
+	if p.client == nil {
+		p.client = newHttpClient(newHttpTransport())
+	}
+	return checkPrometheusResource(ctx, p.client, p.Resource)
+}
+
+// Close releases the connections kept between attempts.
+func (p *PrometheusTargetChecker) Close() error {
+	if p.client != nil {
+		p.client.CloseIdleConnections()
+	}
+	return nil
+}
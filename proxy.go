@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/http/httpproxy"
+)
+
+var (
+	httpProxy = flag.String("http-proxy", "", "Proxy for HTTP-based checks, overriding HTTP_PROXY/HTTPS_PROXY (hosts matching NO_PROXY still connect directly)")
+)
+
+func getenvAny(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// proxyFunc returns the proxy selection used by HTTP-based checkers. Without
+// --http-proxy this is the usual environment-based selection; with it, the
+// given proxy replaces HTTP_PROXY and HTTPS_PROXY while NO_PROXY keeps
+// applying per target host.
+func proxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	if *httpProxy == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	if _, err := url.Parse(*httpProxy); err != nil {
+		return nil, errors.Wrap(err, "invalid --http-proxy")
+	}
+
+	cfg := &httpproxy.Config{
+		HTTPProxy:  *httpProxy,
+		HTTPSProxy: *httpProxy,
+		NoProxy:    getenvAny("NO_PROXY", "no_proxy"),
+	}
+	proxy := cfg.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestProxyFuncNoProxy(t *testing.T) {
+	setFlag(t, httpProxy, "http://proxy.example.com:3128")
+	t.Setenv("NO_PROXY", "internal.example.com,.svc.cluster.local,10.0.0.0/8")
+	t.Setenv("no_proxy", "")
+
+	proxy, err := proxyFunc()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		url       string
+		wantProxy bool
+	}{
+		{"http://api.example.com/health", true},
+		{"https://api.example.com/health", true},
+		{"http://internal.example.com/health", false},
+		{"http://db.internal.example.com/health", false},
+		{"http://notinternal.example.com/health", true},
+		{"http://web.default.svc.cluster.local/", false},
+		{"http://10.1.2.3:8080/", false},
+		{"http://192.168.1.1:8080/", true},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest("GET", tt.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		u, err := proxy(req)
+		if err != nil {
+			t.Fatalf("proxy(%s) = %v", tt.url, err)
+		}
+		if got := u != nil; got != tt.wantProxy {
+			t.Errorf("proxy(%s) = %v, want proxied %v", tt.url, u, tt.wantProxy)
+		}
+		if u != nil && u.Host != "proxy.example.com:3128" {
+			t.Errorf("proxy(%s) = %v, want the --http-proxy", tt.url, u)
+		}
+	}
+}
+
+func TestProxyFuncLowercaseNoProxy(t *testing.T) {
+	setFlag(t, httpProxy, "http://proxy.example.com:3128")
+	t.Setenv("NO_PROXY", "")
+	t.Setenv("no_proxy", "internal.example.com")
+
+	proxy, err := proxyFunc()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", "http://internal.example.com/", nil)
+	if u, _ := proxy(req); u != nil {
+		t.Errorf("proxy(%s) = %v, want a direct connection", req.URL, u)
+	}
+	req, _ = http.NewRequest("GET", "http://external.example.com/", nil)
+	if u, _ := proxy(req); u == nil {
+		t.Errorf("proxy(%s) = nil, want the --http-proxy", req.URL)
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+)
+
+var (
+	maxRate = flag.Float64("max-rate", 0, "Maximum number of check attempts per second across all resources (0 for no limit)")
+)
+
+var probeLimiterOnce sync.Once
+var probeLimiterValue *rate.Limiter
+
+// probeLimiter returns the limiter shared by every wait for --max-rate, or
+// nil when there is no limit.
+func probeLimiter() *rate.Limiter {
+	probeLimiterOnce.Do(func() {
+		if *maxRate > 0 {
+			probeLimiterValue = rate.NewLimiter(rate.Limit(*maxRate), 1)
+		}
+	})
+	return probeLimiterValue
+}
+
+func validateRateFlag() error {
+	if *maxRate < 0 {
+		return errors.Errorf("invalid --max-rate %v, must not be negative", *maxRate)
+	}
+	return nil
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	redisCluster = flag.Bool("redis-cluster", false, "Require CLUSTER INFO of redis:// resources to report cluster_state:ok, asking the listed seed nodes in turn")
+	redisKey     = flag.String("redis-key", "", "Also require this key to exist in redis:// resources, e.g. a flag set once a cache is warm")
+	redisValue   = flag.String("redis-value", "", "Require the value of --redis-key to equal this string")
+)
+
+// errRedisNil is returned for the nil reply to GET of a missing key.
+var errRedisNil = errors.New("nil reply")
+
+func validateRedisFlags() error {
+	if *redisValue != "" && *redisKey == "" {
+		return errors.New("--redis-value requires --redis-key")
+	}
+	return nil
+}
+
+func isRedisResource(resource string) bool {
+	return strings.HasPrefix(resource, "redis://") || strings.HasPrefix(resource, "rediss://")
+}
+
+// redisTarget is a parsed redis://[[user]:password@]host1:port,host2:port[/db]
+// resource.
+type redisTarget struct {
+	TLS      bool
+	User     string
+	Password string
+	Nodes    []string
+	// DB is the database number selected after connecting, if any.
+	DB string
+}
+
+// parseRedisResource splits a resource into its nodes, defaulting to port
+// 6379. url.Parse rejects the comma-separated host list, so this is done by
+// hand like for zk:// resources.
+func parseRedisResource(resource string) (redisTarget, error) {
+	target := redisTarget{TLS: strings.HasPrefix(resource, "rediss://")}
+	rest := strings.TrimPrefix(strings.TrimPrefix(resource, "rediss://"), "redis://")
+	if i := strings.IndexAny(rest, "/?"); i != -1 {
+		if rest[i] == '/' {
+			target.DB, _, _ = strings.Cut(rest[i+1:], "?")
+		}
+		rest = rest[:i]
+	}
+	if target.DB != "" {
+		if n, err := strconv.Atoi(target.DB); err != nil || n < 0 {
+			return target, errors.Errorf("invalid redis database %q", target.DB)
+		}
+	}
+	if at := strings.LastIndex(rest, "@"); at != -1 {
+		// Like in other URLs, reserved characters in the credentials are
+		// percent-encoded.
+		user, password, _ := strings.Cut(rest[:at], ":")
+		var err error
+		if target.User, err = url.PathUnescape(user); err != nil {
+			return target, errors.Wrap(err, "invalid redis user")
+		}
+		if target.Password, err = url.PathUnescape(password); err != nil {
+			return target, errors.Wrap(err, "invalid redis password")
+		}
+		rest = rest[at+1:]
+	}
+	for _, host := range strings.Split(rest, ",") {
+		if host == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(host, "6379")
+		}
+		target.Nodes = append(target.Nodes, host)
+	}
+	if len(target.Nodes) == 0 {
+		return target, errors.New("at least one redis node is required")
+	}
+	return target, nil
+}
+
+// redisConn speaks just enough RESP for the readiness commands.
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dialRedis(ctx context.Context, target redisTarget, address string) (*redisConn, error) {
+	conn, err := dialTCP(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	if target.TLS {
+		host, _, _ := net.SplitHostPort(address)
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: host})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return nil, errors.Wrap(err, "redis tls handshake failed")
+		}
+		conn = tlsConn
+	}
+	rc := &redisConn{conn: conn, r: bufio.NewReader(conn)}
+
+	if target.Password != "" {
+		args := []string{"AUTH", target.Password}
+		if target.User != "" {
+			args = []string{"AUTH", target.User, target.Password}
+		}
+		if _, err := rc.do(args...); err != nil {
+			_ = conn.Close()
+			if isRedisAuthError(err) {
+				return nil, newAuthError(errors.Wrap(err, "redis rejected the credentials"))
+			}
+			return nil, errors.Wrap(err, "redis AUTH failed")
+		}
+	}
+	if target.DB != "" && target.DB != "0" {
+		if _, err := rc.do("SELECT", target.DB); err != nil {
+			_ = conn.Close()
+			if isRedisAuthError(err) {
+				return nil, newAuthError(errors.Wrap(err, "redis requires authentication"))
+			}
+			return nil, newConfigError(errors.Wrapf(err, "failed to select redis database %s", target.DB))
+		}
+	}
+	return rc, nil
+}
+
+func (c *redisConn) Close() error {
+	return c.conn.Close()
+}
+
+// redisConns keeps a connection per node across attempts, for
+// --reuse-connection. A nil redisConns dials a new connection every time.
+type redisConns map[string]*redisConn
+
+func (p redisConns) get(ctx context.Context, target redisTarget, address string) (*redisConn, error) {
+	if conn, ok := p[address]; ok {
+		_ = conn.conn.SetDeadline(protocolDeadline(ctx))
+		return conn, nil
+	}
+	conn, err := dialRedis(ctx, target, address)
+	if err != nil {
+		return nil, err
+	}
+	if p != nil {
+		p[address] = conn
+	}
+	return conn, nil
+}
+
+// release closes conn unless it is kept. Like for Postgres, a failure drops
+// a kept connection, which may have been left with a reply unread.
+func (p redisConns) release(address string, conn *redisConn, err error) {
+	if p != nil && err == nil {
+		return
+	}
+	delete(p, address)
+	_ = conn.Close()
+}
+
+// do sends a command and returns its simple, integer, or bulk string reply.
+// Error replies are returned as errors, and a nil bulk string as errRedisNil.
+func (c *redisConn) do(args ...string) (string, error) {
+	var cmd strings.Builder
+	_, _ = fmt.Fprintf(&cmd, "*%d\r\n", len(args))
+	for _, arg := range args {
+		_, _ = fmt.Fprintf(&cmd, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := c.conn.Write([]byte(cmd.String())); err != nil {
+		return "", errors.Wrapf(err, "failed to send %s", args[0])
+	}
+
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read %s reply", args[0])
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return "", errors.Errorf("empty %s reply", args[0])
+	}
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", errors.New(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if n == -1 {
+			return "", errRedisNil
+		}
+		if err != nil || n < 0 {
+			return "", errors.Errorf("unexpected %s reply %q", args[0], line)
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return "", errors.Wrapf(err, "failed to read %s reply", args[0])
+		}
+		return string(buf[:n]), nil
+	default:
+		return "", errors.Errorf("unexpected %s reply %q", args[0], line)
+	}
+}
+
+func isRedisAuthError(err error) bool {
+	msg := err.Error()
+	return strings.HasPrefix(msg, "WRONGPASS") || strings.HasPrefix(msg, "NOAUTH") || strings.Contains(msg, "invalid password")
+}
+
+// redisPing requires the node to answer PING, which also catches servers that
+// are still loading their dataset.
+func redisPing(ctx context.Context, conns redisConns, target redisTarget, address string) (err error) {
+	conn, err := conns.get(ctx, target, address)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		conns.release(address, conn, err)
+	}()
+
+	reply, err := conn.do("PING")
+	if err != nil {
+		if isRedisAuthError(err) {
+			return newAuthError(errors.Wrap(err, "redis requires authentication"))
+		}
+		return errors.Wrap(err, "redis PING failed")
+	}
+	if reply != "PONG" {
+		return errors.Errorf("unexpected PING reply %q", reply)
+	}
+	if *redisKey != "" {
+		return checkRedisKey(ctx, conns, target, conn, address, true)
+	}
+	return nil
+}
+
+// checkRedisKey requires --redis-key to exist, using GET instead of EXISTS
+// when its value must match --redis-value. A missing key or different value
+// is retried, since it is what a cache that is still warming up reports. In
+// a cluster, a MOVED reply is followed once to the node owning the key.
+func checkRedisKey(ctx context.Context, conns redisConns, target redisTarget, conn *redisConn, address string, followMoved bool) error {
+	var reply string
+	var err error
+	if *redisValue == "" {
+		reply, err = conn.do("EXISTS", *redisKey)
+	} else {
+		reply, err = conn.do("GET", *redisKey)
+	}
+	if err != nil && followMoved && strings.HasPrefix(err.Error(), "MOVED ") {
+		fields := strings.Fields(err.Error())
+		owner := fields[len(fields)-1]
+		logVerbose("redis key %s is served by %s", *redisKey, owner)
+		return checkRedisOwnerKey(ctx, conns, target, owner)
+	}
+	switch {
+	case errors.Is(err, errRedisNil):
+		return errors.Errorf("redis key %s does not exist", *redisKey)
+	case err != nil && strings.HasPrefix(err.Error(), "WRONGTYPE"):
+		return newConfigError(errors.Wrapf(err, "redis key %s does not hold a string", *redisKey))
+	case err != nil:
+		return errors.Wrapf(err, "failed to read redis key %s", *redisKey)
+	}
+
+	if *redisValue == "" {
+		if reply == "0" {
+			return errors.Errorf("redis key %s does not exist", *redisKey)
+		}
+		logVerbose("redis key %s exists on %s", *redisKey, address)
+		return nil
+	}
+	logVerbose("redis key %s is %s on %s", *redisKey, strconv.Quote(reply), address)
+	if reply != *redisValue {
+		return errors.Errorf("redis key %s is %s, want %s", *redisKey, strconv.Quote(reply), strconv.Quote(*redisValue))
+	}
+	return nil
+}
+
+// checkRedisOwnerKey reads --redis-key from owner, the node a MOVED reply
+// redirected to.
+func checkRedisOwnerKey(ctx context.Context, conns redisConns, target redisTarget, owner string) (err error) {
+	conn, err := conns.get(ctx, target, owner)
+	if err != nil {
+		return errors.Wrapf(err, "redis node %s", owner)
+	}
+	defer func() {
+		conns.release(owner, conn, err)
+	}()
+	return checkRedisKey(ctx, conns, target, conn, owner, false)
+}
+
+// redisClusterInfo returns the fields of CLUSTER INFO as reported by one node.
+func redisClusterInfo(ctx context.Context, conns redisConns, target redisTarget, address string) (info map[string]string, err error) {
+	conn, err := conns.get(ctx, target, address)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		conns.release(address, conn, err)
+	}()
+
+	reply, err := conn.do("CLUSTER", "INFO")
+	if err != nil {
+		if strings.Contains(err.Error(), "cluster support disabled") {
+			return nil, newConfigError(errors.Wrap(err, "--redis-cluster requires redis to run in cluster mode"))
+		}
+		if isRedisAuthError(err) {
+			return nil, newAuthError(errors.Wrap(err, "redis requires authentication"))
+		}
+		return nil, errors.Wrap(err, "redis CLUSTER INFO failed")
+	}
+	info = make(map[string]string)
+	for _, line := range strings.Split(reply, "\n") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(line), ":"); ok {
+			info[key] = value
+		}
+	}
+	return info, nil
+}
+
+// checkRedisCluster asks the seed nodes in turn until one answers CLUSTER
+// INFO, and requires it to report the cluster as ok. A failed state is
+// retried, since it is what a forming cluster reports.
+func checkRedisCluster(ctx context.Context, conns redisConns, target redisTarget) error {
+	var lastErr error
+	for _, node := range target.Nodes {
+		info, err := redisClusterInfo(ctx, conns, target, node)
+		if err != nil {
+			if isConfigError(err) {
+				return errors.Wrapf(err, "redis node %s", node)
+			}
+			logVerbose("redis node %s did not answer CLUSTER INFO: %v", node, err)
+			lastErr = errors.Wrapf(err, "redis node %s", node)
+			continue
+		}
+		state := info["cluster_state"]
+		logVerbose("redis node %s reports cluster_state:%s with %s of 16384 slots assigned and %s ok, %s known nodes",
+			node, state, info["cluster_slots_assigned"], info["cluster_slots_ok"], info["cluster_known_nodes"])
+		if state != "ok" {
+			return errors.Errorf("redis cluster state is %s according to %s (%s of 16384 slots ok)", state, node, info["cluster_slots_ok"])
+		}
+		if *redisKey != "" {
+			return checkRedisClusterKey(ctx, conns, target, node)
+		}
+		return nil
+	}
+	return lastErr
+}
+
+// checkRedisClusterKey looks --redis-key up through node, which redirects to
+// the node owning its slot.
+func checkRedisClusterKey(ctx context.Context, conns redisConns, target redisTarget, node string) (err error) {
+	conn, err := conns.get(ctx, target, node)
+	if err != nil {
+		return errors.Wrapf(err, "redis node %s", node)
+	}
+	defer func() {
+		conns.release(node, conn, err)
+	}()
+	return checkRedisKey(ctx, conns, target, conn, node, true)
+}
+
+// checkRedisResource requires every listed node to answer PING, or with
+// --redis-cluster, the cluster to be formed. With --redis-key, the key must be
+// found on every listed node, or in the cluster. Connections are kept in conns
+// when it is not nil.
+func checkRedisResource(ctx context.Context, conns redisConns, resource string) error {
+	cappedCtx, cancel := context.WithTimeout(ctx, checkTimeout(ctx))
+	defer cancel()
+
+	target, err := parseRedisResource(resource)
+	if err != nil {
+		return newConfigError(err)
+	}
+
+	if *redisCluster {
+		return checkRedisCluster(cappedCtx, conns, target)
+	}
+
+	for _, node := range target.Nodes {
+		if err := redisPing(cappedCtx, conns, target, node); err != nil {
+			return errors.Wrapf(err, "redis node %s", node)
+		}
+	}
+	return nil
+}
+
+type RedisChecker struct {
+	Resource string
+
+	conns redisConns
+}
+
+var _ ResourceChecker = (*RedisChecker)(nil)
+
+func (r *RedisChecker) Check(ctx context.Context) error {
+	if *reuseConnection && r.conns == nil {
+		r.conns = make(redisConns)
+	}
+	return checkRedisResource(ctx, r.conns, r.Resource)
+}
+
+// Close releases the connections kept with --reuse-connection.
+func (r *RedisChecker) Close() error {
+	for address, conn := range r.conns {
+		_ = conn.Close()
+		delete(r.conns, address)
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRedis answers RESP commands through handle, which returns the raw
+// reply, and records the commands it received.
+type fakeRedis struct {
+	addr   string
+	handle func(args []string) string
+
+	mu       sync.Mutex
+	commands []string
+	conns    int
+}
+
+func newFakeRedis(t *testing.T, handle func(args []string) string) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	srv := &fakeRedis{addr: ln.Addr().String(), handle: handle}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go srv.serve(conn)
+		}
+	}()
+	return srv
+}
+
+func (s *fakeRedis) serve(conn net.Conn) {
+	s.mu.Lock()
+	s.conns++
+	s.mu.Unlock()
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		args := make([]string, n)
+		for i := range args {
+			header, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			size, _ := strconv.Atoi(strings.TrimSpace(header[1:]))
+			buf := make([]byte, size+2)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return
+			}
+			args[i] = string(buf[:size])
+		}
+		s.mu.Lock()
+		s.commands = append(s.commands, strings.Join(args, " "))
+		s.mu.Unlock()
+		_, _ = conn.Write([]byte(s.handle(args)))
+	}
+}
+
+func (s *fakeRedis) received(command string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.commands {
+		if c == command {
+			return true
+		}
+	}
+	return false
+}
+
+// redisKeys answers PING, SELECT, EXISTS, and GET from keys.
+func redisKeys(keys map[string]string) func(args []string) string {
+	return func(args []string) string {
+		switch strings.ToUpper(args[0]) {
+		case "PING":
+			return "+PONG\r\n"
+		case "SELECT":
+			return "+OK\r\n"
+		case "EXISTS":
+			if _, ok := keys[args[1]]; ok {
+				return ":1\r\n"
+			}
+			return ":0\r\n"
+		case "GET":
+			value, ok := keys[args[1]]
+			if !ok {
+				return "$-1\r\n"
+			}
+			if value == "<hash>" {
+				return "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"
+			}
+			return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+		}
+		return "-ERR unknown command\r\n"
+	}
+}
+
+func TestRedisKey(t *testing.T) {
+	srv := newFakeRedis(t, redisKeys(map[string]string{"warm": "yes", "h": "<hash>"}))
+	tests := []struct {
+		name       string
+		key, value string
+		wantErr    bool
+		wantConfig bool
+	}{
+		{"existing key", "warm", "", false, false},
+		{"missing key", "cold", "", true, false},
+		{"matching value", "warm", "yes", false, false},
+		{"different value", "warm", "no", true, false},
+		{"missing key with value", "cold", "yes", true, false},
+		{"wrong type", "h", "x", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, redisKey, tt.key)
+			setFlag(t, redisValue, tt.value)
+			err := checkRedisResource(context.Background(), nil, "redis://"+srv.addr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkRedisResource() = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && isConfigError(err) != tt.wantConfig {
+				t.Errorf("isConfigError(%v) = %v, want %v", err, isConfigError(err), tt.wantConfig)
+			}
+		})
+	}
+}
+
+func TestRedisKeySelectsDatabase(t *testing.T) {
+	srv := newFakeRedis(t, redisKeys(map[string]string{"warm": "yes"}))
+	setFlag(t, redisKey, "warm")
+	if err := checkRedisResource(context.Background(), nil, "redis://"+srv.addr+"/3"); err != nil {
+		t.Fatalf("checkRedisResource() = %v, want nil", err)
+	}
+	if !srv.received("SELECT 3") {
+		t.Error("database 3 was not selected")
+	}
+	if _, err := parseRedisResource("redis://localhost/db"); err == nil {
+		t.Error("parseRedisResource() accepted a database that is not a number")
+	}
+}
+
+func TestRedisCredentialsAreUnescaped(t *testing.T) {
+	srv := newFakeRedis(t, func(args []string) string {
+		if strings.ToUpper(args[0]) == "AUTH" {
+			return "+OK\r\n"
+		}
+		return redisKeys(nil)(args)
+	})
+	if err := checkRedisResource(context.Background(), nil, "redis://app%3A1:p%40ss@"+srv.addr); err != nil {
+		t.Fatalf("checkRedisResource() = %v, want nil", err)
+	}
+	if !srv.received("AUTH app:1 p@ss") {
+		t.Error("the credentials were not percent-decoded")
+	}
+	if _, err := parseRedisResource("redis://:p%zz@localhost"); err == nil {
+		t.Error("parseRedisResource() accepted an invalid escape in the password")
+	}
+}
+
+func TestRedisClusterKeyFollowsMoved(t *testing.T) {
+	owner := newFakeRedis(t, redisKeys(map[string]string{"warm": "yes"}))
+	seed := newFakeRedis(t, func(args []string) string {
+		switch strings.ToUpper(args[0]) {
+		case "CLUSTER":
+			info := "cluster_state:ok\r\ncluster_slots_ok:16384\r\n"
+			return fmt.Sprintf("$%d\r\n%s\r\n", len(info), info)
+		case "GET", "EXISTS":
+			return "-MOVED 12539 " + owner.addr + "\r\n"
+		}
+		return "-ERR unknown command\r\n"
+	})
+	setFlag(t, redisCluster, true)
+	setFlag(t, redisKey, "warm")
+	setFlag(t, redisValue, "yes")
+	if err := checkRedisResource(context.Background(), nil, "redis://"+seed.addr); err != nil {
+		t.Fatalf("checkRedisResource() = %v, want nil", err)
+	}
+	if !owner.received("GET warm") {
+		t.Error("the key was not read from the node owning it")
+	}
+}
+
+func (s *fakeRedis) connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conns
+}
+
+func TestRedisCheckerReuseConnection(t *testing.T) {
+	keys := map[string]string{}
+	var mu sync.Mutex
+	handle := redisKeys(keys)
+	srv := newFakeRedis(t, func(args []string) string {
+		mu.Lock()
+		defer mu.Unlock()
+		return handle(args)
+	})
+	setFlag(t, reuseConnection, true)
+	setFlag(t, redisKey, "warm")
+
+	checker := &RedisChecker{Resource: "redis://" + srv.addr}
+	defer checker.Close()
+	// The missing key fails the first attempt, which drops the connection.
+	if err := checker.Check(context.Background()); err == nil {
+		t.Fatal("Check() = nil, want an error for the missing key")
+	}
+	mu.Lock()
+	keys["warm"] = "yes"
+	mu.Unlock()
+	for i := 0; i < 3; i++ {
+		if err := checker.Check(context.Background()); err != nil {
+			t.Fatalf("Check() = %v, want nil", err)
+		}
+	}
+	if n := srv.connections(); n != 2 {
+		t.Errorf("checks opened %d connections, want 2", n)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	resourcesFile = flag.String("resources-file", "", "File of newline-separated [name=]resources to wait for, in addition to any given as arguments")
+)
+
+// resourceSpec is a resource given on the command line along with the name it
+// is reported under.
+type resourceSpec struct {
+	Name     string
+	Resource string
+	// Timeout and Interval override --timeout and the check interval when
+	// set, from a manifest entry or a resource's @duration suffix.
+	Timeout  time.Duration
+	Interval time.Duration
+	// Checker replaces the checker picked by the resource's scheme.
+	Checker ResourceChecker
+}
+
+// waitTimeout is the resource's own timeout, or --timeout, with --deadline
+// replacing --timeout. A resource's own timeout never runs past --deadline,
+// whichever comes first ends the wait.
+func (s resourceSpec) waitTimeout() time.Duration {
+	if deadlineAt.IsZero() {
+		if s.Timeout > 0 {
+			return s.Timeout
+		}
+		return time.Second * time.Duration(*timeout)
+	}
+	untilDeadline := time.Until(deadlineAt)
+	if s.Timeout > 0 && s.Timeout < untilDeadline {
+		return s.Timeout
+	}
+	return untilDeadline
+}
+
+func (s resourceSpec) interval() time.Duration {
+	if s.Interval > 0 {
+		return s.Interval
+	}
+	return checkInterval
+}
+
+func (s resourceSpec) checker() ResourceChecker {
+	if s.Checker != nil {
+		return s.Checker
+	}
+	return checkerForResource(s.Resource)
+}
+
+// parseResourceArg accepts either a bare resource or name=resource. An '='
+// only introduces a name when it appears before the scheme separator, so
+// query strings such as ?sslmode=disable are left alone. Either form may end
+// in @duration, e.g. @30s, to give the resource its own timeout.
+func parseResourceArg(arg string) resourceSpec {
+	var timeout time.Duration
+	if at := strings.LastIndex(arg, "@"); at != -1 {
+		// A host following user info never parses as a duration.
+		if d, err := time.ParseDuration(arg[at+1:]); err == nil && d > 0 {
+			arg, timeout = arg[:at], d
+		}
+	}
+	eq := strings.Index(arg, "=")
+	sep := strings.Index(arg, "://")
+	if eq > 0 && (sep == -1 || eq < sep) {
+		return resourceSpec{Name: arg[:eq], Resource: arg[eq+1:], Timeout: timeout}
+	}
+	return resourceSpec{Name: redactResource(arg), Resource: arg, Timeout: timeout}
+}
+
+// redactResource reduces a resource to its scheme, host, and path so that
+// credentials in the user info or query string never end up in the output.
+func redactResource(resource string) string {
+	u, err := url.Parse(resource)
+	if err != nil || u.Scheme == "" {
+		if at := strings.LastIndex(resource, "@"); at != -1 {
+			if sep := strings.Index(resource, "://"); sep != -1 && sep < at {
+				return resource[:sep+3] + resource[at+1:]
+			}
+		}
+		return resource
+	}
+	return u.Scheme + "://" + u.Host + u.Path
+}
+
+// readResourcesFile parses one [name=]resource per line, skipping blank lines
+// and lines starting with '#'. Every invalid line is reported, each error
+// pointing at its line, and no resources are returned if there is one.
+func readResourcesFile(path string) ([]resourceSpec, []error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, []error{errors.Wrap(err, "failed to open resources file")}
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var specs []resourceSpec
+	var errs []error
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "=") {
+			errs = append(errs, errors.Errorf("%s:%d: empty resource name", path, lineNo))
+			continue
+		}
+		spec := parseResourceArg(line)
+		if spec.Resource == "" {
+			errs = append(errs, errors.Errorf("%s:%d: missing resource for %s", path, lineNo, spec.Name))
+			continue
+		}
+		expanded, err := expandSpec(spec)
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "%s:%d", path, lineNo))
+			continue
+		}
+		for _, spec := range expanded {
+			if checkerForResource(spec.Resource) == nil {
+				errs = append(errs, errors.Errorf("%s:%d: unsupported resource type: %s", path, lineNo, redactResource(spec.Resource)))
+			}
+		}
+		specs = append(specs, expanded...)
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, errors.Wrap(err, "failed to read resources file"))
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return specs, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadResourcesFileReportsEveryInvalidLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resources.txt")
+	content := strings.Join([]string{
+		"# comment",
+		"api=http://localhost:8080/health",
+		"tcp://localhost:1234",
+		"",
+		"=http://localhost",
+		"db=",
+		"cache=nats://cache-[1-2]:4222",
+		"redis://localhost:6379",
+	}, "\n")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	specs, errs := readResourcesFile(path)
+	if specs != nil {
+		t.Errorf("readResourcesFile() returned %d resources along with errors", len(specs))
+	}
+	want := []string{
+		path + ":3: unsupported resource type: tcp://localhost:1234",
+		path + ":5: empty resource name",
+		path + ":6: missing resource for db",
+		path + ":7: unsupported resource type: nats://cache-1:4222",
+		path + ":7: unsupported resource type: nats://cache-2:4222",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("readResourcesFile() = %v, want %d errors", errs, len(want))
+	}
+	for i, err := range errs {
+		if err.Error() != want[i] {
+			t.Errorf("error %d = %q, want %q", i, err, want[i])
+		}
+	}
+}
+
+func TestReadResourcesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resources.txt")
+	if err := os.WriteFile(path, []byte("api=http://localhost:8080/health\nredis://cache-[1-2]:6379\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	specs, errs := readResourcesFile(path)
+	if len(errs) > 0 {
+		t.Fatalf("readResourcesFile() = %v", errs)
+	}
+	var names []string
+	for _, spec := range specs {
+		names = append(names, spec.Name)
+	}
+	if got, want := strings.Join(names, " "), "api redis://cache-1:6379 redis://cache-2:6379"; got != want {
+		t.Errorf("names = %q, want %q", got, want)
+	}
+}
+
+func TestWaitTimeoutCappedByDeadline(t *testing.T) {
+	setFlag(t, &deadlineAt, time.Now().Add(time.Minute))
+
+	if got := (resourceSpec{Timeout: 10 * time.Minute}).waitTimeout(); got > time.Minute {
+		t.Errorf("waitTimeout() = %s for @10m, want it capped by the deadline a minute away", got)
+	}
+	if got := (resourceSpec{Timeout: 5 * time.Second}).waitTimeout(); got != 5*time.Second {
+		t.Errorf("waitTimeout() = %s for @5s, want 5s before the deadline", got)
+	}
+	if got := (resourceSpec{}).waitTimeout(); got > time.Minute || got < 50*time.Second {
+		t.Errorf("waitTimeout() = %s without a timeout, want the time left until the deadline", got)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	sshAuth = flag.Bool("ssh-auth", false, "Require ssh:// resources to accept public key authentication instead of only reading the banner")
+	sshKey  = flag.String("ssh-key", "", "Private key used by --ssh-auth")
+)
+
+func isSshResource(resource string) bool {
+	return strings.HasPrefix(resource, "ssh://")
+}
+
+func sshAddress(u *url.URL) string {
+	if u.Port() == "" {
+		return net.JoinHostPort(u.Hostname(), "22")
+	}
+	return u.Host
+}
+
+// wrapSshDialError separates "nothing is listening yet" from other network
+// failures, since the former is the expected state while a host boots.
+func wrapSshDialError(err error) error {
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return errors.Wrap(err, "ssh connection refused")
+	}
+	return err
+}
+
+// readSshBanner reads the server identification line. Servers may send other
+// lines before it (RFC 4253 section 4.2).
+func readSshBanner(conn net.Conn) (string, error) {
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return "", errors.Wrap(err, "ssh protocol error: failed to read banner")
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(line, "SSH-") {
+			return line, nil
+		}
+	}
+}
+
+func checkSshResource(ctx context.Context, resource string) error {
+	cappedCtx, cancel := context.WithTimeout(ctx, checkTimeout(ctx))
+	defer cancel()
+
+	u, err := url.Parse(resource)
+	if err != nil {
+		return newConfigError(errors.Wrap(err, "failed to parse ssh url"))
+	}
+
+	conn, err := dialTCP(cappedCtx, sshAddress(u))
+	if err != nil {
+		return wrapSshDialError(err)
+	}
+	stop := context.AfterFunc(cappedCtx, func() {
+		_ = conn.Close()
+	})
+	defer stop()
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if !*sshAuth {
+		banner, err := readSshBanner(conn)
+		if err != nil {
+			return err
+		}
+		logVerbose("ssh server identified as %s", banner)
+		return nil
+	}
+
+	if *sshKey == "" {
+		return newConfigError(errors.New("--ssh-auth requires --ssh-key"))
+	}
+	keyPEM, err := os.ReadFile(*sshKey)
+	if err != nil {
+		return newConfigError(errors.Wrap(err, "failed to read ssh key"))
+	}
+	signer, err := ssh.ParsePrivateKey(keyPEM)
+	if err != nil {
+		return newConfigError(errors.Wrap(err, "failed to parse ssh key"))
+	}
+
+	user := "root"
+	if u.User != nil {
+		user = u.User.Username()
+	}
+	config := &ssh.ClientConfig{
+		User: user,
+		Auth: []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		// The probe never runs commands, so the host key is not verified.
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, sshAddress(u), config)
+	if err != nil {
+		if strings.Contains(err.Error(), "unable to authenticate") {
+			return newAuthError(errors.Wrap(err, "ssh authentication rejected"))
+		}
+		return errors.Wrap(err, "ssh handshake failed")
+	}
+	go ssh.DiscardRequests(reqs)
+	go func() {
+		for ch := range chans {
+			_ = ch.Reject(ssh.Prohibited, "")
+		}
+	}()
+	logVerbose("ssh server identified as %s", sshConn.ServerVersion())
+	logConnection("%s: server version %s", sshAddress(u), sshConn.ServerVersion())
+
+	return sshConn.Close()
+}
+
+type SshChecker struct {
+	Resource string
+}
+
+var _ ResourceChecker = (*SshChecker)(nil)
+
+func (s *SshChecker) Check(ctx context.Context) error {
+	return checkSshResource(ctx, s.Resource)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+var (
+	statsdAddr = flag.String("statsd-addr", "", "StatsD/DogStatsD UDP address to send per-resource wait metrics to, e.g. localhost:8125")
+)
+
+// statsdTagValue strips the characters that delimit DogStatsD tags.
+func statsdTagValue(s string) string {
+	return strings.NewReplacer(",", "_", "|", "_", "#", "_", ":", "_", "\n", "_").Replace(s)
+}
+
+// sendStatsdMetrics sends an awfi.check.duration timer and an
+// awfi.check.attempts counter per resource, tagged by resource name. Metrics
+// are best effort, so failures are only logged with --verbose.
+func sendStatsdMetrics(addr string, results []resourceResult) {
+	conn, err := net.DialTimeout("udp", addr, time.Second)
+	if err != nil {
+		logVerbose("failed to send statsd metrics: %v", err)
+		return
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	for _, result := range results {
+		tags := fmt.Sprintf("#resource:%s,scheme:%s,ready:%t", statsdTagValue(result.Name), statsdTagValue(result.Scheme), result.Ready)
+		metrics := []string{
+			fmt.Sprintf("awfi.check.duration:%d|ms|%s", result.Duration.Milliseconds(), tags),
+			fmt.Sprintf("awfi.check.attempts:%d|c|%s", result.Attempts, tags),
+		}
+		for _, metric := range metrics {
+			// One metric per datagram keeps each packet well below the MTU.
+			if _, err := conn.Write([]byte(metric)); err != nil {
+				logVerbose("failed to send statsd metric: %v", err)
+				return
+			}
+		}
+	}
+}
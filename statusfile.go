@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	statusFile       = flag.String("status-file", "", "Write \"ready\" or \"failed\" to this file once the run ends, for wrappers that cannot read exit codes")
+	statusFileFormat = flag.String("status-file-format", "text", "Format of --status-file: text or json, which adds the result of every resource")
+)
+
+func validStatusFileFormat(format string) bool {
+	switch format {
+	case "text", "json":
+		return true
+	default:
+		return false
+	}
+}
+
+// writeStatusFile replaces path through a rename, so readers never see a
+// partially written status.
+func writeStatusFile(path string, ready bool, results []resourceResult) error {
+	status := "failed"
+	if ready {
+		status = "ready"
+	}
+
+	content := []byte(status + "\n")
+	if *statusFileFormat == "json" {
+		var err error
+		content, err = json.MarshalIndent(struct {
+			Status    string           `json:"status"`
+			Resources []resourceResult `json:"resources"`
+		}{status, results}, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "failed to encode status file")
+		}
+		content = append(content, '\n')
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return errors.Wrap(err, "failed to create status file")
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return errors.Wrap(err, "failed to write status file")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "failed to write status file")
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return errors.Wrap(err, "failed to write status file")
+	}
+	return errors.Wrap(os.Rename(tmp.Name(), path), "failed to write status file")
+}
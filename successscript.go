@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	successScript = flag.String("success-script", "", "Run this command after every HTTP response that passes the other checks, with the status code and body on stdin; the attempt succeeds only if it exits with 0")
+)
+
+// validateSuccessScript fails early when --success-script cannot be run.
+func validateSuccessScript() error {
+	if *successScript == "" {
+		return nil
+	}
+	if _, err := exec.LookPath(*successScript); err != nil {
+		return errors.Wrap(err, "invalid --success-script")
+	}
+	return nil
+}
+
+// runSuccessScript feeds the status code on the first line, followed by the
+// body, to --success-script. The script is killed when ctx ends.
+func runSuccessScript(ctx context.Context, resp *http.Response, body []byte) error {
+	var stdin bytes.Buffer
+	_, _ = fmt.Fprintf(&stdin, "%d\n", resp.StatusCode)
+	_, _ = stdin.Write(body)
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, *successScript)
+	cmd.Stdin = &stdin
+	cmd.Stderr = &stderr
+	// Children of a killed script could otherwise keep stderr open, and the
+	// attempt waiting, past the deadline.
+	cmd.WaitDelay = time.Second
+	err := cmd.Run()
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return errors.Wrap(ctx.Err(), "success script did not finish")
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return newConfigError(errors.Wrap(err, "failed to run success script"))
+	}
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return errors.Errorf("success script exited with status %d: %s", exitErr.ExitCode(), msg)
+	}
+	return errors.Errorf("success script exited with status %d", exitErr.ExitCode())
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	outputFormat = flag.String("output", "text", "Output format: text, json, or k8s-condition")
+	quiet        = flag.Bool("quiet", false, "Suppress output when every resource becomes ready")
+	summaryOnly  = flag.Bool("summary-only", false, "Suppress per-attempt logs but always print the final summary, even on success")
+	reportSlow   = flag.Bool("report-slowest", false, "Report the resource that took the longest to become ready and the total wait time")
+)
+
+func validOutputFormat(format string) bool {
+	switch format {
+	case "text", "json", "k8s-condition":
+		return true
+	default:
+		return false
+	}
+}
+
+// resourceResult is the final outcome of waiting for a single resource.
+type resourceResult struct {
+	Name     string        `json:"name"`
+	Scheme   string        `json:"scheme"`
+	Ready    bool          `json:"ready"`
+	Attempts int           `json:"attempts"`
+	Duration time.Duration `json:"-"`
+	Err      error         `json:"-"`
+	// Skipped is set for group members whose wait was stopped because their
+	// group was already decided.
+	Skipped bool `json:"skipped,omitempty"`
+	// Transition is set for HTTP resources waited for with
+	// --http-from-status.
+	Transition *statusTransition `json:"transition,omitempty"`
+	// Flaps counts the failed checks during --confirm-window.
+	Flaps int `json:"flaps,omitempty"`
+
+	DurationSeconds float64 `json:"duration_seconds"`
+	Error           string  `json:"error,omitempty"`
+}
+
+func resourceScheme(resource string) string {
+	if i := strings.Index(resource, "://"); i != -1 {
+		return resource[:i]
+	}
+	return ""
+}
+
+func newResourceResult(spec resourceSpec, stats waitStats, err error) resourceResult {
+	result := resourceResult{
+		Name:            spec.Name,
+		Scheme:          resourceScheme(spec.Resource),
+		Ready:           err == nil,
+		Attempts:        stats.Attempts,
+		Duration:        stats.Duration,
+		Err:             err,
+		DurationSeconds: stats.Duration.Seconds(),
+		Flaps:           stats.Flaps,
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// slowestResult returns the ready resource that took the longest, or false
+// when none became ready.
+func slowestResult(results []resourceResult) (resourceResult, bool) {
+	var slowest resourceResult
+	found := false
+	for _, result := range results {
+		if result.Ready && (!found || result.Duration > slowest.Duration) {
+			slowest, found = result, true
+		}
+	}
+	return slowest, found
+}
+
+// summaryReport is the --output=json document printed with --report-slowest
+// or --retry-budget.
+type summaryReport struct {
+	Resources []resourceResult `json:"resources"`
+	// Slowest is only set with --report-slowest, and omitted when no
+	// resource became ready.
+	Slowest      *slowestResource `json:"slowest,omitempty"`
+	TotalSeconds float64          `json:"total_seconds"`
+	// RetryBudget is set with --retry-budget.
+	RetryBudget *budgetUsage `json:"retry_budget,omitempty"`
+}
+
+type budgetUsage struct {
+	Limit int `json:"limit"`
+	Used  int `json:"used"`
+}
+
+type slowestResource struct {
+	Name            string  `json:"name"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+func newSummaryReport(results []resourceResult, total time.Duration) summaryReport {
+	report := summaryReport{Resources: results, TotalSeconds: total.Seconds()}
+	if slowest, ok := slowestResult(results); ok && *reportSlow {
+		report.Slowest = &slowestResource{Name: slowest.Name, DurationSeconds: slowest.DurationSeconds}
+	}
+	if budget := probeBudget(); budget != nil {
+		used, limit := budget.usage()
+		report.RetryBudget = &budgetUsage{Limit: limit, Used: used}
+	}
+	return report
+}
+
+func writeSlowestLine(w io.Writer, results []resourceResult, total time.Duration) {
+	total = total.Round(time.Millisecond)
+	slowest, ok := slowestResult(results)
+	if !ok {
+		_, _ = fmt.Fprintf(w, "no resource became ready, total %s\n", total)
+		return
+	}
+	_, _ = fmt.Fprintf(w, "slowest: %s took %s, total %s\n", slowest.Name, slowest.Duration.Round(time.Millisecond), total)
+}
+
+// reportResults prints the final results to w and reports whether every
+// resource became ready. total is the wall-clock time of the whole wait, for
+// --report-slowest.
+func reportResults(w io.Writer, results []resourceResult, total time.Duration) bool {
+	allReady := true
+	for _, result := range results {
+		allReady = allReady && (result.Ready || result.Skipped)
+	}
+
+	if *outputFormat == "json" || *outputFormat == "k8s-condition" {
+		if allReady && *quiet && !*summaryOnly {
+			return allReady
+		}
+		if *outputFormat == "k8s-condition" {
+			writeConditions(w, results)
+			return allReady
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if *reportSlow || probeBudget() != nil {
+			_ = enc.Encode(newSummaryReport(results, total))
+			return allReady
+		}
+		_ = enc.Encode(results)
+		return allReady
+	}
+
+	for _, result := range results {
+		if result.Flaps > 0 {
+			printColored(os.Stderr, colorYellow, "warning: %s became available but flapped %d times within --confirm-window", result.Name, result.Flaps)
+		}
+		if result.Ready {
+			if t := result.Transition; t != nil {
+				logVerboseColored(colorGreen, "%s is ready, went from %d to %d in %.3fs", result.Name, t.From, t.To, t.Seconds)
+				continue
+			}
+			logVerboseColored(colorGreen, "%s is ready", result.Name)
+			continue
+		}
+		if result.Skipped {
+			continue
+		}
+		if len(results) == 1 && !*summaryOnly {
+			printColored(w, colorRed, "%s: %v", result.Name, result.Err)
+		}
+		if githubAnnotationsEnabled() {
+			writeGithubError(w, result.Name, result.Err)
+		}
+	}
+
+	if *summaryOnly || (len(results) > 1 && !(allReady && *quiet)) {
+		writeSummaryTable(w, results)
+	}
+	if *reportSlow && !(allReady && *quiet) {
+		writeSlowestLine(w, results, total)
+	}
+	if budget := probeBudget(); budget != nil && !(allReady && *quiet) {
+		used, limit := budget.usage()
+		_, _ = fmt.Fprintf(w, "retry budget: used %d of %d attempts\n", used, limit)
+	}
+
+	return allReady
+}
+
+func writeSummaryTable(w io.Writer, results []resourceResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "NAME\tSCHEME\tREADY\tATTEMPTS\tDURATION\tLAST ERROR")
+	for _, result := range results {
+		// Every READY cell is colorized the same way so the escape codes do
+		// not throw off the column widths.
+		ready := colorize(w, colorGreen, "yes")
+		lastErr := ""
+		if result.Skipped {
+			ready = colorize(w, colorYellow, "skipped")
+		} else if !result.Ready {
+			ready = colorize(w, colorRed, "no")
+			// The attempt count already has its own column.
+			lastErr = result.Error
+			var giveUp *giveUpError
+			if errors.As(result.Err, &giveUp) && giveUp.LastErr != nil {
+				lastErr = giveUp.LastErr.Error()
+			}
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\n",
+			result.Name,
+			result.Scheme,
+			ready,
+			result.Attempts,
+			result.Duration.Round(time.Millisecond),
+			lastErr,
+		)
+	}
+	_ = tw.Flush()
+}
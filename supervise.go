@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	keepRunning      = flag.Bool("keep-running", false, "Once every resource is ready, keep checking them until SIGTERM or SIGINT, exiting with 1 if one stays unavailable for longer than --keep-running-grace")
+	keepRunningGrace = flag.Duration("keep-running-grace", 30*time.Second, "How long a resource may stay unavailable under --keep-running before awfi gives up")
+)
+
+// superviseResources re-checks the resources that became ready at their
+// interval until a termination signal, which ends it cleanly with status 0, or
+// until one of them stays unavailable for longer than the grace period.
+func superviseResources(specs []resourceSpec, checkers []ResourceChecker, results []resourceResult) int {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, len(specs))
+	var wg sync.WaitGroup
+	for i := range specs {
+		if !results[i].Ready {
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := superviseResource(ctx, specs[i], checkers[i], *keepRunningGrace); err != nil {
+				errs <- err
+				cancel()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	select {
+	case err := <-errs:
+		printColored(os.Stdout, colorRed, "%v", err)
+		return 1
+	default:
+		return 0
+	}
+}
+
+// superviseResource re-checks the resource every interval. Each check is
+// capped by the resource's own timeout or --timeout, like those of the wait.
+func superviseResource(ctx context.Context, spec resourceSpec, checker ResourceChecker, grace time.Duration) error {
+	ctx = withCheckTimeout(ctx, spec.Timeout)
+	var downSince time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(spec.interval()):
+		}
+
+		err := cappedCheck(ctx, checker)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err == nil {
+			if !downSince.IsZero() {
+				printColored(os.Stderr, colorGreen, "%s is ready again after %s", spec.Name, time.Since(downSince).Round(time.Second))
+				downSince = time.Time{}
+			}
+			continue
+		}
+		if downSince.IsZero() {
+			printColored(os.Stderr, colorYellow, "%s lost readiness: %v", spec.Name, err)
+			downSince = time.Now()
+		}
+		if time.Since(downSince) >= grace {
+			return errors.Wrapf(err, "%s has been unavailable for more than %s", spec.Name, grace)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// hangingChecker blocks until its context ends, like a server that accepts
+// connections and never answers.
+type hangingChecker struct{}
+
+func (hangingChecker) Check(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestSuperviseResourceCapsEachCheck(t *testing.T) {
+	spec := resourceSpec{Name: "stalled", Interval: 10 * time.Millisecond, Timeout: 50 * time.Millisecond}
+	done := make(chan error, 1)
+	go func() {
+		done <- superviseResource(context.Background(), spec, hangingChecker{}, 100*time.Millisecond)
+	}()
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "stalled has been unavailable") {
+			t.Errorf("superviseResource() = %v, want the resource to be given up on", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("superviseResource() is stuck in a check")
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	otelEndpoint    = flag.String("otel-endpoint", "", "OTLP/HTTP endpoint to export trace spans to, e.g. http://localhost:4318 (tracing is disabled when unset)")
+	httpTraceHeader = flag.String("http-trace-header", "", "Header carrying a W3C traceparent on HTTP checks, e.g. traceparent (sent automatically when --otel-endpoint is set)")
+)
+
+// tracer delegates to the global provider, so it is a no-op until
+// setupTracing installs an exporting one.
+var tracer = otel.Tracer("github.com/parrotmac/awfi")
+
+// setupTracing installs an OTLP exporting tracer provider when
+// --otel-endpoint is set. The returned function flushes pending spans.
+func setupTracing(ctx context.Context) (func(context.Context) error, error) {
+	if *otelEndpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	// The exporter only logs an unparseable endpoint and falls back to
+	// localhost, so it is checked here.
+	endpoint, err := url.Parse(*otelEndpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, errors.Errorf("invalid --otel-endpoint %q, expected an http or https URL", *otelEndpoint)
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(*otelEndpoint))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create otlp exporter")
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName("awfi"),
+	))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create otel resource")
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return provider.Shutdown, nil
+}
+
+func attemptAttributes(spec resourceSpec, attempt int) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("awfi.resource", spec.Name),
+		attribute.String("awfi.scheme", resourceScheme(spec.Resource)),
+		attribute.Int("awfi.attempt", attempt),
+	}
+}
+
+// newTraceparent returns a W3C traceparent for a new, sampled trace. Trace
+// and parent IDs must not be all zeros, which crypto/rand output practically
+// never is, but it is checked anyway.
+func newTraceparent() string {
+	var traceID trace.TraceID
+	var spanID trace.SpanID
+	for !traceID.IsValid() {
+		_, _ = rand.Read(traceID[:])
+	}
+	for !spanID.IsValid() {
+		_, _ = rand.Read(spanID[:])
+	}
+	return "00-" + hex.EncodeToString(traceID[:]) + "-" + hex.EncodeToString(spanID[:]) + "-01"
+}
+
+// setTraceHeader attaches trace context to an HTTP check. With tracing
+// enabled, the attempt span's context is propagated; otherwise a fresh
+// traceparent is generated on every request when --http-trace-header is set.
+func setTraceHeader(ctx context.Context, req *http.Request) {
+	traceparent := ""
+	if *otelEndpoint != "" {
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+		traceparent = req.Header.Get("traceparent")
+	}
+	if *httpTraceHeader == "" {
+		return
+	}
+	if traceparent == "" {
+		traceparent = newTraceparent()
+	}
+	req.Header.Set(*httpTraceHeader, traceparent)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// dashboardRefresh is how often the dashboard is redrawn.
+const dashboardRefresh = 250 * time.Millisecond
+
+// dashboardErrWidth keeps rows from wrapping, which would break redrawing in
+// place.
+const dashboardErrWidth = 60
+
+var (
+	tui = flag.Bool("tui", false, "Show a live-updating table of resources while waiting (only when stdout is a terminal)")
+)
+
+type dashboardRow struct {
+	name     string
+	scheme   string
+	state    string
+	attempts int
+	elapsed  time.Duration
+	done     bool
+	lastErr  string
+}
+
+// dashboard renders one row per resource, redrawn in place with ANSI escape
+// codes.
+type dashboard struct {
+	mu    sync.Mutex
+	w     io.Writer
+	start time.Time
+	rows  []dashboardRow
+	// lines is the number of lines drawn by the last render.
+	lines int
+}
+
+func newDashboard(w io.Writer, specs []resourceSpec) *dashboard {
+	d := &dashboard{w: w, start: time.Now(), rows: make([]dashboardRow, len(specs))}
+	for i, spec := range specs {
+		d.rows[i] = dashboardRow{name: spec.Name, scheme: resourceScheme(spec.Resource), state: "waiting"}
+	}
+	return d
+}
+
+func (d *dashboard) update(i int, result CheckResult) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	row := &d.rows[i]
+	row.attempts = result.Attempt
+	row.elapsed = result.Elapsed
+	switch {
+	case result.Done && result.Err == nil:
+		row.state, row.done, row.lastErr = "ready", true, ""
+	case result.Done:
+		row.state, row.done = "not ready", true
+	case result.Err == nil:
+		row.state = "passing"
+	default:
+		row.state, row.lastErr = "failing", result.Err.Error()
+	}
+}
+
+func (d *dashboard) render() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "NAME\tSCHEME\tSTATE\tATTEMPTS\tELAPSED\tLAST ERROR")
+	for _, row := range d.rows {
+		elapsed := row.elapsed
+		if !row.done {
+			elapsed = time.Since(d.start)
+		}
+		lastErr := row.lastErr
+		if len(lastErr) > dashboardErrWidth {
+			lastErr = lastErr[:dashboardErrWidth-3] + "..."
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\n",
+			row.name, row.scheme, row.state, row.attempts, elapsed.Round(100*time.Millisecond), lastErr)
+	}
+	_ = tw.Flush()
+
+	d.erase()
+	_, _ = d.w.Write(buf.Bytes())
+	d.lines = strings.Count(buf.String(), "\n")
+}
+
+// erase removes the last render; the caller holds the lock.
+func (d *dashboard) erase() {
+	if d.lines > 0 {
+		_, _ = fmt.Fprintf(d.w, "\033[%dA\033[J", d.lines)
+		d.lines = 0
+	}
+}
+
+// run redraws the dashboard until stop is closed, then erases it so the
+// final summary takes its place.
+func (d *dashboard) run(stop <-chan struct{}) {
+	ticker := time.NewTicker(dashboardRefresh)
+	defer ticker.Stop()
+	for {
+		d.render()
+		select {
+		case <-stop:
+			d.mu.Lock()
+			d.erase()
+			d.mu.Unlock()
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runValidate implements "awfi validate": the files given with --manifest,
+// --resources-file, and --groups-file are loaded and checked without running
+// any check. Problems are printed with their location, and any error exits
+// with exitConfigError, like invalid files given to a wait.
+func runValidate(args []string) {
+	_ = flag.CommandLine.Parse(args)
+
+	if *manifestFile == "" && *resourcesFile == "" && *groupsFile == "" {
+		fmt.Println("validate requires --manifest, --resources-file, or --groups-file")
+		flag.Usage()
+		os.Exit(exitConfigError)
+	}
+
+	valid := true
+	report := func(path string, errs []error, warnings []string) {
+		for _, err := range errs {
+			fmt.Println(colorize(os.Stdout, colorRed, "error: ") + err.Error())
+		}
+		for _, warning := range warnings {
+			fmt.Println(colorize(os.Stdout, colorYellow, "warning: ") + warning)
+		}
+		if len(errs) > 0 {
+			valid = false
+		} else if !*quiet {
+			fmt.Printf("%s is valid\n", path)
+		}
+	}
+
+	if *manifestFile != "" {
+		_, errs, warnings := checkManifest(*manifestFile)
+		report(*manifestFile, errs, warnings)
+	}
+	if *resourcesFile != "" {
+		_, errs := readResourcesFile(*resourcesFile)
+		report(*resourcesFile, errs, nil)
+	}
+	if *groupsFile != "" {
+		_, _, errs := readGroupsFile(*groupsFile)
+		report(*groupsFile, errs, nil)
+	}
+
+	if !valid {
+		os.Exit(exitConfigError)
+	}
+}
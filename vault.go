@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	vaultStandbyOk = flag.Bool("vault-standby-ok", false, "Consider an unsealed standby Vault node as available")
+)
+
+func isVaultResource(resource string) bool {
+	return strings.HasPrefix(resource, "vault://") || strings.HasPrefix(resource, "vaults://")
+}
+
+// vaultHealthURL maps vault://host:port to the plain HTTP health endpoint and
+// vaults://host:port to the HTTPS one.
+func vaultHealthURL(resource string) string {
+	if strings.HasPrefix(resource, "vaults://") {
+		return "https://" + strings.TrimPrefix(resource, "vaults://") + "/v1/sys/health"
+	}
+	return "http://" + strings.TrimPrefix(resource, "vault://") + "/v1/sys/health"
+}
+
+func checkVaultResource(ctx context.Context, cx *http.Client, resource string) error {
+	cappedCtx, cancel := context.WithTimeout(ctx, checkTimeout(ctx))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(cappedCtx, "GET", vaultHealthURL(resource), nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+
+	resp, err := cx.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to perform request")
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	_, err = io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "failed to read response body")
+	}
+
+	// See https://developer.hashicorp.com/vault/api-docs/system/health for the
+	// meaning of each status code.
+	switch resp.StatusCode {
+	case http.StatusOK:
+		logVerbose("vault is initialized, unsealed, and active")
+		return nil
+	case http.StatusTooManyRequests, 473:
+		if *vaultStandbyOk {
+			logVerbose("vault is unsealed and in standby")
+			return nil
+		}
+		return errors.New("vault is in standby")
+	case 472:
+		return errors.New("vault is a disaster recovery secondary")
+	case http.StatusNotImplemented:
+		return errors.New("vault is not initialized")
+	case http.StatusServiceUnavailable:
+		return errors.New("vault is sealed")
+	default:
+		return errors.Errorf("unexpected vault health status code %d", resp.StatusCode)
+	}
+}
+
+type VaultChecker struct {
+	Resource string
+
+	// client is kept across attempts so connections can be reused.
+	client *http.Client
+}
+
+var _ ResourceChecker = (*VaultChecker)(nil)
+
+func (v *VaultChecker) Check(ctx context.Context) error {
+	if v.client == nil {
+		v.client = newHttpClient(newHttpTransport())
+	}
+	return checkVaultResource(ctx, v.client, v.Resource)
+}
+
+// Close releases the connections kept between attempts.
+func (v *VaultChecker) Close() error {
+	if v.client != nil {
+		v.client.CloseIdleConnections()
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestVaultCheckerReusesConnection(t *testing.T) {
+	var conns atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	checker := &VaultChecker{Resource: "vault://" + strings.TrimPrefix(srv.URL, "http://")}
+	for i := 0; i < 3; i++ {
+		if err := checker.Check(context.Background()); err != nil {
+			t.Fatalf("attempt %d: Check() = %v, want nil", i+1, err)
+		}
+	}
+	if err := checker.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := conns.Load(); n != 1 {
+		t.Errorf("checks opened %d connections, want 1", n)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// CheckResult is the outcome of a single attempt, or of the whole wait when
+// Done is set.
+type CheckResult struct {
+	// Attempt is the attempt number, or the total number of attempts when
+	// Done is set.
+	Attempt int
+	// Elapsed is the time since the wait started.
+	Elapsed time.Duration
+	// Latency is how long the attempt's check took. It is zero when Done is
+	// set.
+	Latency time.Duration
+	// Err is the attempt's error, or the wait's when Done is set. It is nil
+	// for successful attempts and when the resource became available.
+	Err  error
+	Done bool
+	// Flaps is the number of failed checks during the confirm window. It is
+	// only set when Done is.
+	Flaps int
+}
+
+// streamResource waits for a resource like waitForResource, emitting a result
+// per attempt followed by a final result with Done set. The channel is closed
+// once the wait completes, and must be drained until then.
+func streamResource(ctx context.Context, spec resourceSpec, checker ResourceChecker, opts waitOptions) <-chan CheckResult {
+	results := make(chan CheckResult)
+	go func() {
+		defer close(results)
+		stats, err := watchResource(ctx, spec, checker, opts, func(result CheckResult) {
+			results <- result
+		})
+		results <- CheckResult{Attempt: stats.Attempts, Elapsed: stats.Duration, Err: err, Done: true, Flaps: stats.Flaps}
+	}()
+	return results
+}
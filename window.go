@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	windowSize      = flag.Int("window-size", 0, "Number of recent attempts --window-successes is counted over, instead of requiring --repeated-successes in a row (0 to disable)")
+	windowSuccesses = flag.Int("window-successes", 0, "Number of successful attempts among the last --window-size needed to consider the resource available")
+)
+
+func validateWindowFlags() error {
+	if *windowSize == 0 && *windowSuccesses == 0 {
+		return nil
+	}
+	if *windowSize <= 0 || *windowSuccesses <= 0 {
+		return errors.New("--window-size and --window-successes must be used together and be positive")
+	}
+	if *windowSuccesses > *windowSize {
+		return errors.Errorf("--window-successes %d cannot exceed --window-size %d", *windowSuccesses, *windowSize)
+	}
+	if *repeatedSuccesses != 1 || *failureThreshold != 1 {
+		return errors.New("--window-size cannot be combined with --repeated-successes or --failure-threshold")
+	}
+	return nil
+}
+
+// outcomeWindow is a ring buffer of the outcomes of the last attempts.
+type outcomeWindow struct {
+	outcomes []bool
+	next     int
+	count    int
+}
+
+func newOutcomeWindow(size int) *outcomeWindow {
+	return &outcomeWindow{outcomes: make([]bool, size)}
+}
+
+func (w *outcomeWindow) record(ok bool) {
+	w.outcomes[w.next] = ok
+	w.next = (w.next + 1) % len(w.outcomes)
+	if w.count < len(w.outcomes) {
+		w.count++
+	}
+}
+
+// successes counts the successful attempts among those remembered. Before the
+// window fills up, the missing attempts count as failures.
+func (w *outcomeWindow) successes() int {
+	n := 0
+	for i := 0; i < w.count; i++ {
+		if w.outcomes[i] {
+			n++
+		}
+	}
+	return n
+}
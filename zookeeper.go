@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"flag"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	zkSessionFallback = flag.Bool("zk-session-fallback", false, "Establish a session with zk:// servers when the ruok command is disabled or unanswered")
+)
+
+func isZookeeperResource(resource string) bool {
+	return strings.HasPrefix(resource, "zk://")
+}
+
+// zookeeperServers splits zk://host1:port,host2:port/chroot into addresses.
+func zookeeperServers(resource string) []string {
+	hosts := strings.TrimPrefix(resource, "zk://")
+	if i := strings.Index(hosts, "/"); i != -1 {
+		hosts = hosts[:i]
+	}
+
+	var servers []string
+	for _, host := range strings.Split(hosts, ",") {
+		if host == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(host, "2181")
+		}
+		servers = append(servers, host)
+	}
+	return servers
+}
+
+func zookeeperRuok(ctx context.Context, address string) error {
+	conn, err := dialTCP(ctx, address)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if _, err := conn.Write([]byte("ruok")); err != nil {
+		return errors.Wrap(err, "failed to send ruok")
+	}
+
+	// The server closes the connection after answering.
+	reply, err := io.ReadAll(conn)
+	if err != nil {
+		return errors.Wrap(err, "failed to read ruok reply")
+	}
+	if string(reply) != "imok" {
+		return errors.Errorf("unexpected ruok reply %q", string(reply))
+	}
+	return nil
+}
+
+// zookeeperMaxConnectResponse bounds the length of a ConnectResponse, which is
+// 37 bytes with its 16-byte password.
+const zookeeperMaxConnectResponse = 64
+
+// zookeeperSession performs the connect handshake of the client protocol and
+// closes the session again. Servers that are not part of a serving quorum
+// drop the connection instead of assigning a session.
+
+func zookeeperSession(ctx context.Context, address string) error {
+	conn, err := dialTCP(ctx, address)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	// ConnectRequest: protocolVersion, lastZxidSeen, timeOut, sessionId,
+	// passwd (length-prefixed), readOnly.
+	req := make([]byte, 4+4+8+4+8+4+16+1)
+	binary.BigEndian.PutUint32(req[0:], uint32(len(req)-4))
+	binary.BigEndian.PutUint32(req[16:], 30000)
+	binary.BigEndian.PutUint32(req[28:], 16)
+	if _, err := conn.Write(req); err != nil {
+		return errors.Wrap(err, "failed to send connect request")
+	}
+
+	var length uint32
+	if err := binary.Read(conn, binary.BigEndian, &length); err != nil {
+		return errors.Wrap(err, "server closed the connection without assigning a session")
+	}
+	// The length comes from whatever listens on the port, so it is checked
+	// before allocating: another service's reply would otherwise decode to
+	// up to 4 GiB ("HTTP" alone is about 1.2 GB).
+	if length > zookeeperMaxConnectResponse {
+		return errors.Errorf("invalid connect response length %d, not a zookeeper server", length)
+	}
+	resp := make([]byte, length)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return errors.Wrap(err, "failed to read connect response")
+	}
+	if len(resp) < 16 {
+		return errors.New("short connect response")
+	}
+	// ConnectResponse: protocolVersion, timeOut, sessionId, passwd, readOnly.
+	negotiatedTimeout := binary.BigEndian.Uint32(resp[4:])
+	sessionID := binary.BigEndian.Uint64(resp[8:])
+	if negotiatedTimeout == 0 || sessionID == 0 {
+		return errors.New("server refused to establish a session")
+	}
+
+	// closeSession request: xid, type.
+	closeReq := make([]byte, 12)
+	binary.BigEndian.PutUint32(closeReq[0:], 8)
+	binary.BigEndian.PutUint32(closeReq[4:], 1)
+	binary.BigEndian.PutUint32(closeReq[8:], uint32(0xfffffff5)) // -11
+	_, _ = conn.Write(closeReq)
+
+	return nil
+}
+
+// checkZookeeperResource requires every listed server to be healthy.
+func checkZookeeperResource(ctx context.Context, resource string) error {
+	cappedCtx, cancel := context.WithTimeout(ctx, checkTimeout(ctx))
+	defer cancel()
+
+	servers := zookeeperServers(resource)
+	if len(servers) == 0 {
+		return errors.New("at least one zookeeper server is required")
+	}
+
+	for _, server := range servers {
+		err := zookeeperRuok(cappedCtx, server)
+		if err != nil && *zkSessionFallback {
+			logVerbose("zookeeper %s did not answer ruok (%v), trying a session", server, err)
+			err = zookeeperSession(cappedCtx, server)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "zookeeper server %s", server)
+		}
+	}
+
+	return nil
+}
+
+type ZookeeperChecker struct {
+	Resource string
+}
+
+var _ ResourceChecker = (*ZookeeperChecker)(nil)
+
+func (z *ZookeeperChecker) Check(ctx context.Context) error {
+	return checkZookeeperResource(ctx, z.Resource)
+}
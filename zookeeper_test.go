@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestZookeeperSessionRejectsOtherServices(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.ReadFull(conn, make([]byte, 48))
+		// An HTTP server answering the connect request, whose first bytes
+		// would announce a 1.2 GB response.
+		_, _ = conn.Write([]byte("HTTP/1.1 400 Bad Request\r\n\r\n"))
+	}()
+
+	err = zookeeperSession(context.Background(), ln.Addr().String())
+	if err == nil || !strings.Contains(err.Error(), "not a zookeeper server") {
+		t.Errorf("zookeeperSession() = %v, want an invalid length error", err)
+	}
+}